@@ -15,36 +15,34 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"github.com/laurentganne/yorc-provider-go-client/v1/yorcprovider"
-	"github.com/pkg/errors"
 )
 
 // Command arguments
 var url, user, password, orchestratorName, locationType, locationName string
 
 type queryType struct {
+	pairs  []string
 	params map[string]string
 }
 
 var query queryType
 
 func (q *queryType) String() string {
-	return fmt.Sprintf("%+v", q.params)
+	return fmt.Sprintf("%+v", q.pairs)
 }
 
 func (q *queryType) Set(value string) error {
-	v := strings.Split(value, "=")
-	if len(v) != 2 {
-		return errors.Errorf("Expected query parameter of the form key=value, got %s", value)
+	// Checking the format of the parameter
+	if _, err := yorcprovider.ParseQueryParams([]string{value}); err != nil {
+		return err
 	}
-	q.params[v[0]] = v[1]
+	q.pairs = append(q.pairs, value)
 	return nil
 }
 
@@ -56,7 +54,6 @@ func init() {
 	flag.StringVar(&orchestratorName, "orchestrator", "", "Orchestrator name")
 	flag.StringVar(&locationType, "type", "", "Location type")
 	flag.StringVar(&locationName, "location", "", "Location")
-	query.params = make(map[string]string)
 	flag.Var(&query, "query", "Query parameter of the form \"key=value\" (you can use this flag mutiple times to define multiple query params)")
 }
 
@@ -64,6 +61,11 @@ func main() {
 
 	// Parsing command arguments
 	flag.Parse()
+	params, err := yorcprovider.ParseQueryParams(query.pairs)
+	if err != nil {
+		log.Panic(err)
+	}
+	query.params = params
 
 	// Check required parameters
 	if orchestratorName == "" {
@@ -76,10 +78,13 @@ func main() {
 		log.Panic("Mandatory argument 'location' missing (Name of location for which to get a usage report)")
 	}
 
-	client, err := yorcprovider.NewClient(url, user, password, "", true)
+	client, err := yorcprovider.New(url,
+		yorcprovider.WithCredentials(user, password),
+		yorcprovider.WithInsecureSkipVerify())
 	if err != nil {
 		log.Panic(err)
 	}
+	defer client.Close()
 
 	err = client.Login()
 	if err != nil {
@@ -107,20 +112,11 @@ func main() {
 	}
 
 	// Get the collector for the expected location type
-	var collectorID string
-	collectors, err := client.UsageCollectorService().GetUsageCollectors(orchestratorName)
+	collector, err := client.UsageCollectorService().GetUsageCollector(orchestratorName, locationType)
 	if err != nil {
 		log.Panic(err)
 	}
-	for _, collector := range collectors {
-		if collector.ID == locationType {
-			collectorID = collector.ID
-			break
-		}
-	}
-	if collectorID == "" {
-		log.Panicf("Found no collector for %s on orchestrator %s", locationType, orchestratorName)
-	}
+	collectorID := collector.ID
 
 	// Query a collection of resources usage
 	queryID, err := client.UsageCollectorService().Query(orchestratorName, collectorID, locationName, query.params)
@@ -134,35 +130,24 @@ func main() {
 	var collection *yorcprovider.UsageCollection
 	for !done {
 		time.Sleep(1 * time.Second)
-		collection, err = client.UsageCollectorService().GetCollectedUsage(queryID)
+		collection, err = client.UsageCollectorService().GetCollectedUsage(queryID.String())
 		if err != nil {
 			log.Panic(err)
 		}
 
-		done = (collection.Status == yorcprovider.QueryStatusDone ||
-			collection.Status == yorcprovider.QueryStatusFailed ||
-			collection.Status == yorcprovider.QueryStatusCanceled)
+		done = collection.QueryStatus().IsTerminal()
 	}
 
 	if collection.Status == yorcprovider.QueryStatusDone {
-		fmt.Printf("\ncollection for %s location %s %s:\n%+s\n", orchestratorName, locationName, query.params, prettyPrint(collection.Results))
+		fmt.Printf("\ncollection for %s location %s %s:\n%+s\n", orchestratorName, locationName, query.params, collection.Pretty())
 	} else {
 		fmt.Printf("\nFailed to get collection for %s location %s %s: status %s\n", orchestratorName, locationName, query.params, collection.Status)
 	}
 
 	// Now that the query is done, deleting it
-	err = client.UsageCollectorService().DeleteQuery(queryID)
+	err = client.UsageCollectorService().DeleteQuery(queryID.String())
 	if err != nil {
 		log.Panic(err)
 	}
 
 }
-
-func prettyPrint(v interface{}) string {
-	var result string
-	b, err := json.MarshalIndent(v, "", "  ")
-	if err == nil {
-		result = fmt.Sprintln(string(b))
-	}
-	return result
-}
@@ -4,5 +4,5 @@ go 1.13
 
 require (
 	github.com/goware/urlx v0.3.1
-	github.com/pkg/errors v0.8.1
+	github.com/pkg/errors v0.9.1
 )
@@ -0,0 +1,92 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+)
+
+// GetAggregatedUsage queries the collection of resources usage on a location
+// of several orchestrators, waits for the end of these collections and merges
+// their results.
+// Numeric values having the same key are summed. Other values, or values of a key
+// having different types on orchestrators, are returned side by side in a map
+// keyed by orchestrator name. Nested maps are merged the same way.
+// A failure on an orchestrator doesn't prevent other orchestrators from being
+// aggregated: the aggregation is returned along with a BatchError providing
+// the error per orchestrator
+func (u *usageCollectorService) GetAggregatedUsage(ctx context.Context, collectorID, location string, orchestrators []string) (map[string]interface{}, error) {
+
+	results := make(map[string]interface{})
+	err := u.runConcurrently(ctx, orchestrators, func(ctx context.Context, orchestratorName string) (interface{}, error) {
+		collection, err := u.queryAndWait(ctx, orchestratorName, collectorID, location, nil)
+		if err != nil {
+			return nil, err
+		}
+		return collection.Results, nil
+	}, results)
+
+	aggregation := make(map[string]interface{})
+	if merged, ok := mergeValues(results).(map[string]interface{}); ok {
+		aggregation = merged
+	}
+	return aggregation, err
+}
+
+// mergeValues merges values keyed by orchestrator, summing numeric values
+// and merging maps recursively. Values which can't be merged are returned as is
+func mergeValues(values map[string]interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+
+	allNumbers, allMaps := true, true
+	var sum float64
+	for _, v := range values {
+		switch value := v.(type) {
+		case float64:
+			sum += value
+			allMaps = false
+		case map[string]interface{}:
+			allNumbers = false
+		default:
+			allNumbers, allMaps = false, false
+		}
+	}
+
+	switch {
+	case allNumbers:
+		return sum
+	case allMaps:
+		merged := make(map[string]interface{})
+		keys := make(map[string]bool)
+		for _, v := range values {
+			for k := range v.(map[string]interface{}) {
+				keys[k] = true
+			}
+		}
+		for k := range keys {
+			valuesOfKey := make(map[string]interface{})
+			for orchestratorName, v := range values {
+				if value, ok := v.(map[string]interface{})[k]; ok {
+					valuesOfKey[orchestratorName] = value
+				}
+			}
+			merged[k] = mergeValues(valuesOfKey)
+		}
+		return merged
+	}
+	return values
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import "strings"
+
+// Identifiers of known usage collectors, as provided in the ID or Origin
+// of a UsageCollector
+const (
+	CollectorOpenStack = "openstack"
+	CollectorSlurm     = "slurm"
+	CollectorHostsPool = "hostspool"
+	CollectorGoogle    = "google"
+	CollectorAWS       = "aws"
+)
+
+// CollectorType is the type of a known usage collector
+type CollectorType int
+
+const (
+	// CollectorTypeUnknown is the type of a collector this package doesn't know
+	CollectorTypeUnknown CollectorType = iota
+	// CollectorTypeOpenStack is the type of an OpenStack usage collector
+	CollectorTypeOpenStack
+	// CollectorTypeSlurm is the type of a Slurm usage collector
+	CollectorTypeSlurm
+	// CollectorTypeHostsPool is the type of a Hosts Pool usage collector
+	CollectorTypeHostsPool
+	// CollectorTypeGoogle is the type of a Google Cloud usage collector
+	CollectorTypeGoogle
+	// CollectorTypeAWS is the type of an AWS usage collector
+	CollectorTypeAWS
+)
+
+var collectorTypes = map[string]CollectorType{
+	CollectorOpenStack: CollectorTypeOpenStack,
+	CollectorSlurm:     CollectorTypeSlurm,
+	CollectorHostsPool: CollectorTypeHostsPool,
+	CollectorGoogle:    CollectorTypeGoogle,
+	CollectorAWS:       CollectorTypeAWS,
+}
+
+// ParseCollectorType returns the type of a collector given its identifier,
+// compared without case. CollectorTypeUnknown is returned for an unknown identifier
+func ParseCollectorType(id string) CollectorType {
+	return collectorTypes[strings.ToLower(strings.TrimSpace(id))]
+}
+
+// String returns the identifier of a collector type
+func (t CollectorType) String() string {
+	for id, collectorType := range collectorTypes {
+		if collectorType == t {
+			return id
+		}
+	}
+	return "unknown"
+}
+
+// Type returns the type of a usage collector, given its ID,
+// or else its Origin
+func (c UsageCollector) Type() CollectorType {
+	if t := ParseCollectorType(c.ID); t != CollectorTypeUnknown {
+		return t
+	}
+	return ParseCollectorType(c.Origin)
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"net/http"
+)
+
+// requestContext returns the context of a request, given the context provided
+// by the caller, which is nil or context.Background() when the caller provided no
+// context. When a base context is defined for the client, it is used if the
+// caller provided no context, else values of the base context are available
+// from the context returned, the deadline and cancellation of the caller
+// context being kept
+func (r *restClient) requestContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if r.baseCtx == nil {
+		return ctx
+	}
+	if ctx == context.Background() {
+		return r.baseCtx
+	}
+	return &mergedContext{Context: ctx, base: r.baseCtx}
+}
+
+// mergedContext is a context looking up values in a base context
+// when they are not defined in the context it wraps
+type mergedContext struct {
+	context.Context
+	base context.Context
+}
+
+// Value returns the value associated with key in the wrapped context,
+// or else in the base context
+func (c *mergedContext) Value(key interface{}) interface{} {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.base.Value(key)
+}
+
+type requestHeadersKey struct{}
+
+type collectorOriginKey struct{}
+
+// ContextWithCollectorOrigin returns a context selecting the origin of the
+// collector of queries submitted with this context, when several plugins
+// provide collectors having the same ID. Query helpers, including QueryAll
+// and GetAggregatedUsage, check before submitting a query that its collector
+// is provided by this origin on the orchestrator
+func ContextWithCollectorOrigin(ctx context.Context, origin string) context.Context {
+	return context.WithValue(ctx, collectorOriginKey{}, origin)
+}
+
+// collectorOrigin returns the origin of collectors selected by a context,
+// or an empty string if no origin is selected
+func collectorOrigin(ctx context.Context) string {
+	origin, _ := ctx.Value(collectorOriginKey{}).(string)
+	return origin
+}
+
+// ContextWithRequestHeaders returns a context providing headers added to requests
+// sent with this context, like an idempotency key for a single call.
+// These headers override headers set by the client, including default headers
+// set with WithDefaultHeaders. Headers provided by a parent context are kept,
+// unless overridden by headers having the same key.
+// Login requests sent with this context get these headers as well, including
+// the new login sent when a call gets an unauthorized response. When concurrent
+// calls need a new login, a single login is sent, with the headers of the call
+// sending it
+func ContextWithRequestHeaders(ctx context.Context, headers ...Header) context.Context {
+	all := append(append([]Header(nil), requestHeaders(ctx)...), headers...)
+	return context.WithValue(ctx, requestHeadersKey{}, all)
+}
+
+// requestHeaders returns headers to add to requests sent with a context
+func requestHeaders(ctx context.Context) []Header {
+	headers, _ := ctx.Value(requestHeadersKey{}).([]Header)
+	return headers
+}
+
+// setRequestHeaders sets headers provided by the context of a request.
+// A key defined several times keeps the values defined last
+func setRequestHeaders(request *http.Request) {
+	headers := requestHeaders(request.Context())
+	set := make(map[string]bool)
+	for i := len(headers) - 1; i >= 0; i-- {
+		key := http.CanonicalHeaderKey(headers[i].Key)
+		if set[key] {
+			continue
+		}
+		set[key] = true
+		request.Header.Set(key, headers[i].Value)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRequestHeadersSentWithLogin(t *testing.T) {
+	const key = "X-Idempotency-Key"
+	var lk sync.Mutex
+	var keys []string
+	requests := 0
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lk.Lock()
+		defer lk.Unlock()
+		keys = append(keys, r.Method+" "+r.URL.Path+" "+r.Header.Get(key))
+		if r.URL.Path == "/login" {
+			return
+		}
+		requests++
+		if requests == 1 {
+			// The session expired, a new login is needed
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeTestData(t, w, http.StatusOK, map[string]interface{}{"id": "1", "status": "DONE"})
+	}), WithCredentials("user", "password"))
+	defer server.Close()
+
+	ctx := ContextWithRequestHeaders(context.Background(), Header{Key: key, Value: "42"})
+	if _, err := client.UsageCollectorService().GetCollectedUsageWithContext(ctx, "yorc/infra_usage/slurm/tasks/1"); err != nil {
+		t.Fatalf("GetCollectedUsageWithContext() error = %v", err)
+	}
+	taskPath := DefaultRESTPrefix + "/orchestrators/yorc/infra_usage/slurm/tasks/1"
+	want := []string{"GET " + taskPath + " 42", "POST /login 42", "GET " + taskPath + " 42"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("Requests received = %q, want %q", keys, want)
+	}
+}
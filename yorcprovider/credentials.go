@@ -0,0 +1,55 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"fmt"
+)
+
+// CredentialsProvider provides the credentials used to log in to alien4cloud.
+// It is called on each login, allowing to use credentials rotated by a
+// secrets manager without creating a new client. Implementations must be
+// safe for concurrent use
+type CredentialsProvider interface {
+	// Credentials returns the user name and password to use
+	Credentials(ctx context.Context) (user, password string, err error)
+}
+
+// StaticCredentials is a CredentialsProvider always returning the same credentials
+type StaticCredentials struct {
+	User     string
+	Password string
+}
+
+// Credentials returns the user name and password
+func (s StaticCredentials) Credentials(ctx context.Context) (string, string, error) {
+	return s.User, s.Password, nil
+}
+
+// String returns a description of the credentials, the password being redacted
+func (s StaticCredentials) String() string {
+	password := ""
+	if s.Password != "" {
+		password = redacted
+	}
+	return fmt.Sprintf("StaticCredentials{User: %q, Password: %q}", s.User, password)
+}
+
+// GoString returns a description of the credentials used by the %#v format,
+// the password being redacted
+func (s StaticCredentials) GoString() string {
+	return s.String()
+}
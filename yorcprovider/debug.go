@@ -0,0 +1,123 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DebugEnvVar is the environment variable which, when set to a true value
+// like 1, enables the logging of every request and response on stderr
+const DebugEnvVar = "YORC_CLIENT_DEBUG"
+
+// maxDebugBodySize is the maximum length of a body logged in debug mode
+const maxDebugBodySize = 1024
+
+// sensitiveHeaders are headers whose values are redacted in debug logs
+var sensitiveHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// debugLogger logs requests and responses on stderr
+var debugLogger = log.New(os.Stderr, "[yorc-provider-client] ", log.LstdFlags)
+
+// debugEnabled returns true if the debug mode is enabled in the environment
+func debugEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(DebugEnvVar))
+	return enabled
+}
+
+// debugRequest logs a request, body being the body to log,
+// from which credentials were redacted
+func debugRequest(request *http.Request, body []byte) {
+	debugLogger.Printf("--> %s %s headers: %s body: %s",
+		request.Method, request.URL.String(), formatHeaders(request.Header),
+		truncate(string(body), maxDebugBodySize))
+}
+
+// debugResponse logs the response to a request, or the error that occurred.
+// Only the beginning of the response body is read, not to load a large body
+// in memory, the body being replaced by a reader providing the same content
+func debugResponse(request *http.Request, response *http.Response, duration time.Duration, err error) {
+	if err != nil {
+		debugLogger.Printf("<-- %s %s failed after %s: %s", request.Method, request.URL.String(), duration, err.Error())
+		return
+	}
+
+	// Reading one more byte than logged, for the body to be marked as truncated
+	body := make([]byte, maxDebugBodySize+1)
+	n, readErr := io.ReadFull(response.Body, body)
+	body = body[:n]
+	logged := string(body)
+	var rest io.Reader = response.Body
+	if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+		// The error is returned again once the beginning of the body is read
+		rest = &errorReader{err: readErr}
+		logged = "<unable to read body: " + readErr.Error() + ">"
+	}
+	response.Body = &debugBody{
+		Reader: io.MultiReader(bytes.NewReader(body), rest),
+		Closer: response.Body,
+	}
+	debugLogger.Printf("<-- %s %s status: %d elapsed: %s headers: %s body: %s",
+		request.Method, request.URL.String(), response.StatusCode, duration,
+		formatHeaders(response.Header), truncate(logged, maxDebugBodySize))
+}
+
+// debugBody is a response body of which the beginning was read to be logged
+type debugBody struct {
+	io.Reader
+	io.Closer
+}
+
+// errorReader is a reader failing with an error
+type errorReader struct {
+	err error
+}
+
+// Read returns the error of the reader
+func (e *errorReader) Read(p []byte) (int, error) {
+	return 0, e.err
+}
+
+// formatHeaders returns a string representation of headers where
+// sensitive values are redacted
+func formatHeaders(headers http.Header) string {
+	var keys []string
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var values []string
+	for _, k := range keys {
+		v := strings.Join(headers[k], ",")
+		if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			v = redacted
+		}
+		values = append(values, k+"="+v)
+	}
+	return "[" + strings.Join(values, " ") + "]"
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// enableDebug enables the debug mode for clients created until the returned
+// function is called, debug logs being written in buf
+func enableDebug(buf *bytes.Buffer) func() {
+	os.Setenv(DebugEnvVar, "1")
+	debugLogger.SetOutput(buf)
+	return func() {
+		os.Unsetenv(DebugEnvVar)
+		debugLogger.SetOutput(os.Stderr)
+	}
+}
+
+func TestDebugResponseLogsBodyPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	defer enableDebug(&buf)()
+
+	var orchestrators []Orchestrator
+	for i := 0; i < 200; i++ {
+		orchestrators = append(orchestrators, Orchestrator{Name: fmt.Sprintf("orchestrator-%d", i)})
+	}
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestData(t, w, http.StatusOK, map[string]interface{}{"orchestrators": orchestrators})
+	}))
+	defer server.Close()
+
+	got, err := client.OrchestratorService().GetOrchestrators()
+	if err != nil {
+		t.Fatalf("GetOrchestrators() error = %v", err)
+	}
+	if len(got) != len(orchestrators) {
+		t.Errorf("GetOrchestrators() provided %d orchestrators, want %d", len(got), len(orchestrators))
+	}
+	logs := buf.String()
+	if !strings.Contains(logs, "orchestrator-0") || strings.Contains(logs, "orchestrator-199") {
+		t.Errorf("Debug logs = %s, want only the beginning of the response body", logs)
+	}
+}
+
+func TestDebugResponseWithMaxResponseSize(t *testing.T) {
+	var buf bytes.Buffer
+	defer enableDebug(&buf)()
+
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestData(t, w, http.StatusOK, map[string]interface{}{
+			"orchestrators": []Orchestrator{{Name: strings.Repeat("a", 4*maxDebugBodySize)}},
+		})
+	}), WithMaxResponseSize(100))
+	defer server.Close()
+
+	if _, err := client.OrchestratorService().GetOrchestrators(); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("GetOrchestrators() error = %v, want %v", err, ErrResponseTooLarge)
+	}
+	if logs := buf.String(); !strings.Contains(logs, ErrResponseTooLarge.Error()) {
+		t.Errorf("Debug logs = %s, want the response too large error", logs)
+	}
+}
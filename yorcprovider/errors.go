@@ -0,0 +1,196 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is matched by errors returned when the REST API answers
+// that the resource requested was not found
+var ErrNotFound = errors.New("resource not found")
+
+// ErrNotAuthenticated is matched by errors returned when the client couldn't
+// log in, or when the REST API answers that the request is not authorized
+var ErrNotAuthenticated = errors.New("not authenticated")
+
+// ErrQueryNotFound is matched by errors returned when a resources usage query
+// is not found
+var ErrQueryNotFound = errors.New("query not found")
+
+// ErrOrchestratorNotFound is matched by errors returned when an orchestrator
+// is not found
+var ErrOrchestratorNotFound = errors.New("orchestrator not found")
+
+// ErrCollectorNotFound is matched by errors returned when a usage collector is
+// not found on an orchestrator. errors.As allows to get the CollectorNotFoundError
+// providing the collectors available on this orchestrator
+var ErrCollectorNotFound = errors.New("collector not found")
+
+// ErrClientClosed is the error returned when sending a request with a closed client
+var ErrClientClosed = errors.New("client closed")
+
+// ErrResponseTooLarge is matched by errors returned when the body of a response
+// exceeds the maximum size set with WithMaxResponseSize
+var ErrResponseTooLarge = errors.New("response too large")
+
+// APIError is the error returned when the REST API answers a request
+// with an unexpected HTTP status code
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Code is the application error code provided in the response body, or 0.
+	// Codes are defined by alien4cloud and the yorc collector plugin, and
+	// distinguish error conditions sharing the same HTTP status
+	Code int
+	// Message is the error message provided in the response body, if any
+	Message string
+	// RequestID is the ID of the request, as echoed by the server if it did
+	RequestID string
+	// kind is a sentinel error matched by this error, depending on the request
+	kind error
+	// readErr is the error which occurred reading the response body, if any
+	readErr error
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("%s (HTTP status %d, error code %d)", e.Message, e.StatusCode, e.Code)
+	}
+	return fmt.Sprintf("%s (HTTP status %d)", e.Message, e.StatusCode)
+}
+
+// Is reports whether this error matches target, allowing to check
+// errors.Is(err, ErrNotFound) or errors.Is(err, ErrNotAuthenticated) on an APIError,
+// as well as more specific sentinel errors like ErrQueryNotFound depending on
+// the request
+func (e *APIError) Is(target error) bool {
+	switch {
+	case e.kind != nil && target == e.kind:
+		return true
+	case target == ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case target == ErrNotAuthenticated:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// Unwrap returns the error which occurred reading the body of the response,
+// allowing to check errors.Is(err, ErrResponseTooLarge) on an APIError
+func (e *APIError) Unwrap() error {
+	return e.readErr
+}
+
+// ErrorCode returns the application error code of an APIError wrapped in err.
+// It returns false if err doesn't wrap an APIError, or no code was provided
+func ErrorCode(err error) (int, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code == 0 {
+		return 0, false
+	}
+	return apiErr.Code, true
+}
+
+// IsNotFound returns true if the error is due to a resource not found
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// NetworkError is the error returned when a request couldn't be sent to the
+// REST API or no response was received, because of a transport-level failure
+// like a DNS resolution failure, a refused connection or a TLS handshake error.
+// Contrary to an APIError, no HTTP status was returned by the server
+type NetworkError struct {
+	// Operation is the name of the client method sending the request
+	Operation string
+	Method    string
+	URL       string
+	// RequestID is the ID of the request sent
+	RequestID string
+	Err       error
+}
+
+// Error implements the error interface
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("%s %s failed: %s", e.Method, e.URL, e.Err.Error())
+}
+
+// Unwrap returns the underlying transport error
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout returns true if the error is due to a timeout
+func (e *NetworkError) Timeout() bool {
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// CollectorNotFoundError is the error returned when no usage collector
+// with the expected ID is provided on an orchestrator
+type CollectorNotFoundError struct {
+	Orchestrator string
+	CollectorID  string
+	// Origin is the plugin expected to provide the collector, if any
+	Origin string
+	// Available provides the IDs of collectors provided on the orchestrator
+	Available []string
+}
+
+// Error implements the error interface
+func (e *CollectorNotFoundError) Error() string {
+	if e.Origin != "" {
+		return fmt.Sprintf("No collector %s from %s found on orchestrator %s. Known collectors: %v",
+			e.CollectorID, e.Origin, e.Orchestrator, e.Available)
+	}
+	return fmt.Sprintf("No collector %s found on orchestrator %s. Known collectors: %v",
+		e.CollectorID, e.Orchestrator, e.Available)
+}
+
+// Is reports whether this error matches target, allowing to check
+// errors.Is(err, ErrCollectorNotFound) or errors.Is(err, ErrNotFound)
+// on a CollectorNotFoundError
+func (e *CollectorNotFoundError) Is(target error) bool {
+	return target == ErrCollectorNotFound || target == ErrNotFound
+}
+
+// BatchError gathers the errors that occurred on items of a batch operation,
+// keyed by item, like a location name
+type BatchError struct {
+	Errors map[string]error
+}
+
+// Error implements the error interface
+func (e *BatchError) Error() string {
+	var keys []string
+	for k := range e.Errors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var msgs []string
+	for _, k := range keys {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", k, e.Errors[k].Error()))
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(keys), strings.Join(msgs, "; "))
+}
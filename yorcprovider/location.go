@@ -0,0 +1,84 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// LocationService is the interface to the service managing locations
+type LocationService interface {
+	// Returns the list of locations of an orchestrator
+	GetLocations(orchestratorName string) ([]Location, error)
+}
+
+type locationService struct {
+	client restAPI
+}
+
+// GetLocations returns the list of locations of an orchestrator
+func (l *locationService) GetLocations(orchestratorName string) ([]Location, error) {
+
+	response, err := l.client.do(
+		"GetLocations",
+		"GET",
+		orchestratorPath(l.client.prefix(), orchestratorName)+"/locations",
+		nil,
+		[]Header{
+			{
+				"Content-Type",
+				"application/json",
+			},
+		},
+	)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to send request to get locations of %s", orchestratorName)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, getNotFoundError(response, ErrOrchestratorNotFound)
+	}
+
+	if err = checkJSONResponse(response); err != nil {
+		return nil, err
+	}
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to read response to get locations of %s", orchestratorName)
+	}
+
+	var res struct {
+		Data struct {
+			Locations []Location `json:"locations,omitempty"`
+		} `json:"data"`
+	}
+	if err = l.client.unmarshal(responseBody, &res); err != nil {
+		return nil, errors.Wrapf(err, "Cannot convert the body of response to get locations of %s", orchestratorName)
+	}
+
+	for i := range res.Data.Locations {
+		if res.Data.Locations[i].Orchestrator == "" {
+			res.Data.Locations[i].Orchestrator = orchestratorName
+		}
+	}
+	return res.Data.Locations, err
+}
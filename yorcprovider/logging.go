@@ -0,0 +1,42 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"time"
+)
+
+// redacted is the value replacing credentials in logs
+const redacted = "*****"
+
+// Logger is the interface of a hook notified of requests sent to alien4cloud.
+// Implementations must be safe for concurrent use
+type Logger interface {
+	// LogRequest is called before sending a request
+	LogRequest(req RequestInfo)
+	// LogResponse is called once a response is received or the request failed,
+	// in which case the status code is 0 and err is the error that occurred
+	LogResponse(req RequestInfo, statusCode int, duration time.Duration, err error)
+}
+
+// MetricsRecorder is the interface of a hook recording metrics on requests
+// sent to alien4cloud.
+// The operation is the name of the client method which sent the request,
+// like GetOrchestrators or Query. The status code is 0 when the request failed
+// without any response.
+// Implementations must be safe for concurrent use
+type MetricsRecorder interface {
+	ObserveRequest(operation string, statusCode int, duration time.Duration)
+}
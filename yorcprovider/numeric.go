@@ -0,0 +1,87 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SumNumeric sums numeric values found in the results of a usage collection
+// at a dotted key path like "hosts.cpus". When a value along the path is a
+// list, the rest of the path is followed in each of its elements, so that
+// "hosts.cpus" sums the cpus of all hosts. It returns the sum and the number
+// of numeric values summed, values that are not numeric or keys that are
+// missing being skipped
+func SumNumeric(uc *UsageCollection, keyPath string) (float64, int, error) {
+	if uc == nil {
+		return 0, 0, errors.New("No usage collection to sum values from")
+	}
+	if strings.TrimSpace(keyPath) == "" {
+		return 0, 0, errors.New("Key path of values to sum must not be empty")
+	}
+	var sum float64
+	var count int
+	sumValues(uc.Results, strings.Split(keyPath, "."), &sum, &count)
+	return sum, count, nil
+}
+
+// sumValues adds to sum numeric values found in value at the given path
+func sumValues(value interface{}, path []string, sum *float64, count *int) {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, elem := range v {
+			sumValues(elem, path, sum, count)
+		}
+		return
+	case map[string]interface{}:
+		if len(path) > 0 {
+			if child, ok := v[path[0]]; ok {
+				sumValues(child, path[1:], sum, count)
+			}
+		}
+		return
+	}
+	if len(path) > 0 {
+		// The path goes beyond this value
+		return
+	}
+	if f, ok := toFloat(value); ok {
+		*sum += f
+		*count++
+	}
+}
+
+// toFloat converts a numeric value to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
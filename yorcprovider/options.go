@@ -0,0 +1,517 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ClientOption is an option allowing to configure the Client
+// returned by New
+type ClientOption func(*clientOptions) error
+
+// clientOptions holds the configuration set by client options
+type clientOptions struct {
+	credentials         CredentialsProvider
+	caFile              string
+	skipSecure          bool
+	timeout             time.Duration
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	httpClient          *http.Client
+	restPrefix          string
+	maxConcurrency      int
+	cookieFile          string
+	userAgent           string
+	defaultHeaders      []Header
+	logger              Logger
+	metrics             MetricsRecorder
+	limiter             *rateLimiter
+	autoLogin           bool
+	dialTimeout         time.Duration
+	keepAlive           time.Duration
+	tlsHandshakeTimeout time.Duration
+	strictDecoding      bool
+	baseCtx             context.Context
+	maxTrackedQueries   int
+	proxyURL            *url.URL
+	noProxy             []string
+	dialContext         func(ctx context.Context, network, addr string) (net.Conn, error)
+	maxRetries          int
+	retryBackoff        time.Duration
+	validateQueries     bool
+	basicAuth           bool
+	cookieJar           http.CookieJar
+	maxResponseSize     int64
+	onRetry             RetryFunc
+	defaultQueryParams  url.Values
+	tlsServerName       string
+	cleanupQueries      bool
+}
+
+// WithCredentials sets the credentials of the user logging in to alien4cloud
+func WithCredentials(user, password string) ClientOption {
+	return func(o *clientOptions) error {
+		o.credentials = StaticCredentials{User: user, Password: password}
+		return nil
+	}
+}
+
+// WithCredentialsProvider sets the provider of credentials used to log in
+// to alien4cloud, called on each login. This allows to use credentials
+// rotated by a secrets manager. It replaces credentials set by WithCredentials
+func WithCredentialsProvider(provider CredentialsProvider) ClientOption {
+	return func(o *clientOptions) error {
+		if provider == nil {
+			return errors.New("Credentials provider option must not be nil")
+		}
+		o.credentials = provider
+		return nil
+	}
+}
+
+// WithBasicAuth sets credentials sent with HTTP Basic authentication on each
+// request, for gateways in front of alien4cloud accepting it. No session is
+// used in this mode, Login and Logout doing nothing. WithCredentialsProvider
+// can be used after this option to provide credentials dynamically
+func WithBasicAuth(user, password string) ClientOption {
+	return func(o *clientOptions) error {
+		o.credentials = StaticCredentials{User: user, Password: password}
+		o.basicAuth = true
+		return nil
+	}
+}
+
+// WithAutoLogin enables the automatic login to alien4cloud, using credentials
+// defined with WithCredentials, on the first request sent when no session exists.
+// Login can still be called explicitly to check credentials on startup
+func WithAutoLogin() ClientOption {
+	return func(o *clientOptions) error {
+		o.autoLogin = true
+		return nil
+	}
+}
+
+// WithCAFile sets the file of the certificate authority used to verify
+// the alien4cloud certificate. By default, system certificate authorities are used
+func WithCAFile(path string) ClientOption {
+	return func(o *clientOptions) error {
+		o.caFile = path
+		return nil
+	}
+}
+
+// WithTLSServerName sets the server name sent for TLS SNI and checked against
+// the alien4cloud certificate, instead of the host of the alien4cloud URL.
+// This is needed when connecting through a load balancer whose host differs
+// from the names of the certificate
+func WithTLSServerName(name string) ClientOption {
+	return func(o *clientOptions) error {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("TLS server name option must not be empty")
+		}
+		o.tlsServerName = name
+		return nil
+	}
+}
+
+// WithInsecureSkipVerify disables the verification of the alien4cloud
+// certificate. By default, the certificate is verified
+func WithInsecureSkipVerify() ClientOption {
+	return func(o *clientOptions) error {
+		o.skipSecure = true
+		return nil
+	}
+}
+
+// WithTimeout sets the time limit of requests sent to alien4cloud,
+// including the time to read the response body. By default, there is no timeout
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) error {
+		o.timeout = timeout
+		return nil
+	}
+}
+
+// WithHTTPClient allows to provide the HTTP client used to send requests.
+// When provided, the TLS and timeout options are ignored, the client being
+// used as is, except that the client cookie jar is set when the client has none
+// or when a jar is provided with WithCookieJar.
+// In tests, this allows to send requests to an httptest.Server:
+//
+//	server := httptest.NewServer(handler)
+//	client, err := yorcprovider.New(server.URL, yorcprovider.WithHTTPClient(server.Client()))
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(o *clientOptions) error {
+		if client == nil {
+			return errors.New("HTTP client option must not be nil")
+		}
+		o.httpClient = client
+		return nil
+	}
+}
+
+// WithCookieJar allows to provide the cookie jar storing session cookies,
+// instead of the jar of the client, for example to share a session between
+// several clients. When shared, the jar must be safe for concurrent use,
+// which is the responsibility of the caller. Cookies stored in this jar
+// can't be persisted with WithCookieFile or SaveCookies
+func WithCookieJar(jar http.CookieJar) ClientOption {
+	return func(o *clientOptions) error {
+		if jar == nil {
+			return errors.New("Cookie jar option must not be nil")
+		}
+		o.cookieJar = jar
+		return nil
+	}
+}
+
+// WithCookieFile allows to persist the session cookies in a file, so that
+// a session can be shared between successive runs of a program.
+// Cookies are loaded from this file when the client is created and saved
+// in this file on each successful login. By default, cookies are not persisted
+func WithCookieFile(path string) ClientOption {
+	return func(o *clientOptions) error {
+		o.cookieFile = path
+		return nil
+	}
+}
+
+// WithLogger allows to provide a logger notified of each request sent
+// to alien4cloud and of its response. By default, requests are not logged
+func WithLogger(logger Logger) ClientOption {
+	return func(o *clientOptions) error {
+		o.logger = logger
+		return nil
+	}
+}
+
+// WithMetrics allows to provide a recorder of metrics on requests sent
+// to alien4cloud. By default, no metric is recorded
+func WithMetrics(recorder MetricsRecorder) ClientOption {
+	return func(o *clientOptions) error {
+		o.metrics = recorder
+		return nil
+	}
+}
+
+// WithRESTPrefix sets the path prefix of the yorc collector plugin REST API,
+// allowing to target a given version of the plugin like /rest/yorc-collector-plugin/1.2,
+// or a plugin exposed under another path. By default, DefaultRESTPrefix is used
+func WithRESTPrefix(prefix string) ClientOption {
+	return func(o *clientOptions) error {
+		// Removing duplicate and trailing slashes
+		prefix = path.Clean("/" + strings.TrimSpace(prefix))
+		if prefix == "/" {
+			return errors.New("REST prefix option must not be empty")
+		}
+		o.restPrefix = prefix
+		return nil
+	}
+}
+
+// WithMaxConcurrency sets the maximum number of operations run concurrently by
+// helpers processing a batch of items, like QueryAll. Defaults to DefaultMaxConcurrency
+func WithMaxConcurrency(n int) ClientOption {
+	return func(o *clientOptions) error {
+		if n <= 0 {
+			return errors.Errorf("Maximum concurrency must be positive, got %d", n)
+		}
+		o.maxConcurrency = n
+		return nil
+	}
+}
+
+// WithRateLimit limits the rate of requests sent to alien4cloud to rps requests
+// per second on average, allowing bursts of at most burst requests.
+// A request waiting for the rate limiter stops waiting when its context is done.
+// By default, the rate of requests is not limited
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(o *clientOptions) error {
+		if rps <= 0 || burst <= 0 {
+			return errors.Errorf("Rate limit must be positive, got %f requests per second and a burst of %d", rps, burst)
+		}
+		o.limiter = newRateLimiter(rps, burst)
+		return nil
+	}
+}
+
+// WithMaxIdleConns sets the maximum number of idle connections kept open.
+// By default, there is no limit other than the limit per host.
+// This option is ignored when an HTTP client is provided with WithHTTPClient
+func WithMaxIdleConns(n int) ClientOption {
+	return func(o *clientOptions) error {
+		o.maxIdleConns = n
+		return nil
+	}
+}
+
+// WithMaxIdleConnsPerHost sets the maximum number of idle connections kept
+// open to alien4cloud. Defaults to DefaultMaxIdleConnsPerHost.
+// This option is ignored when an HTTP client is provided with WithHTTPClient
+func WithMaxIdleConnsPerHost(n int) ClientOption {
+	return func(o *clientOptions) error {
+		o.maxIdleConnsPerHost = n
+		return nil
+	}
+}
+
+// WithIdleConnTimeout sets the time after which an idle connection is closed.
+// By default, idle connections are not closed.
+// This option is ignored when an HTTP client is provided with WithHTTPClient
+func WithIdleConnTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) error {
+		o.idleConnTimeout = timeout
+		return nil
+	}
+}
+
+// WithBaseContext sets a base context for all requests sent by the client,
+// allowing to provide values like a tenant ID or tracing data to every request.
+// The base context is used by methods not taking a context, and by methods
+// called with context.Background(). When a method is called with another context,
+// this per-call context wins: its deadline and cancellation apply, values being
+// looked up in the per-call context first, then in the base context
+func WithBaseContext(ctx context.Context) ClientOption {
+	return func(o *clientOptions) error {
+		if ctx == nil {
+			return errors.New("Base context option must not be nil")
+		}
+		o.baseCtx = ctx
+		return nil
+	}
+}
+
+// WithQueryTracking enables the tracking of queries submitted by the client
+// which are not over yet, so that they can be canceled on shutdown by
+// UsageCollectorService.CancelAll. At most max queries are tracked, the oldest
+// ones being forgotten first. A query is not tracked anymore once it is deleted,
+// canceled, or once a final status is returned for it.
+// By default, queries are not tracked
+func WithQueryTracking(max int) ClientOption {
+	return func(o *clientOptions) error {
+		if max <= 0 {
+			return errors.Errorf("Maximum number of tracked queries must be positive, got %d", max)
+		}
+		o.maxTrackedQueries = max
+		return nil
+	}
+}
+
+// WithRetry enables retries of requests failing because of a transient error:
+// a response with status 429 or 503, a response with status 502 or 504 for
+// requests which can be sent again safely, or a transient network error like
+// a temporary DNS failure, a refused connection, or a connection reset for
+// requests which can be sent again safely.
+// A request is sent again at most maxRetries times, waiting backoff before the
+// first retry, then doubling this delay on each retry, unless the response
+// provides a Retry-After header.
+// IsRetryable allows to classify errors returned by the client the same way,
+// 500, 501 and all gateway errors being considered as retryable by IsRetryable.
+// Retries and new logins share a single budget: in the worst case, a call
+// sends maxRetries+2 requests, the request being sent again once after a new
+// login, and one login request.
+// By default, requests are not retried
+func WithRetry(maxRetries int, backoff time.Duration) ClientOption {
+	return func(o *clientOptions) error {
+		if maxRetries < 0 || backoff < 0 {
+			return errors.Errorf("Retry option must not be negative, got %d retries and a backoff of %s", maxRetries, backoff)
+		}
+		o.maxRetries = maxRetries
+		o.retryBackoff = backoff
+		return nil
+	}
+}
+
+// RetryFunc is a function called before a request is sent again, given the
+// number of the retry starting at 1, the request, the error or error response
+// causing the retry, and the delay before sending the request again
+type RetryFunc func(attempt int, request RequestInfo, err error, delay time.Duration)
+
+// WithOnRetry sets a function called before each retry enabled by WithRetry,
+// allowing to log or meter transient failures. It is called concurrently
+// and must be safe for concurrent use
+func WithOnRetry(fn RetryFunc) ClientOption {
+	return func(o *clientOptions) error {
+		o.onRetry = fn
+		return nil
+	}
+}
+
+// WithDefaultQueryParams sets query parameters provided to all queries
+// submitted with query string parameters, like a tenant or a period shared by
+// queries. Parameters provided to a call override default parameters having
+// the same key. Queries submitted with QueryWithBody are not affected
+func WithDefaultQueryParams(params map[string]string) ClientOption {
+	return func(o *clientOptions) error {
+		o.defaultQueryParams = url.Values{}
+		for k, v := range params {
+			o.defaultQueryParams.Set(k, v)
+		}
+		return nil
+	}
+}
+
+// WithQueryCleanup enables the deletion of queries submitted by QueryAll and
+// GetAggregatedUsage once they are done. Queries which failed or were canceled
+// are kept, allowing to inspect them. By default, queries are not deleted
+func WithQueryCleanup() ClientOption {
+	return func(o *clientOptions) error {
+		o.cleanupQueries = true
+		return nil
+	}
+}
+
+// WithQueryValidation enables the validation of queries before submitting them:
+// the collector and the location are checked to exist on the orchestrator,
+// locations being checked only when the plugin provides them. An error
+// matching ErrNotFound is returned before submitting the query otherwise.
+// An error is returned as well if several plugins provide collectors having
+// the same ID, unless an origin is selected with ContextWithCollectorOrigin.
+// This costs additional requests, so queries are not validated by default
+func WithQueryValidation() ClientOption {
+	return func(o *clientOptions) error {
+		o.validateQueries = true
+		return nil
+	}
+}
+
+// WithMaxResponseSize sets the maximum size in bytes of response bodies,
+// reading a larger body failing with an error matching ErrResponseTooLarge.
+// A size lower or equal to 0 means no limit. ContextWithMaxResponseSize allows
+// to change this size for a call. Default is DefaultMaxResponseSize
+func WithMaxResponseSize(size int64) ClientOption {
+	return func(o *clientOptions) error {
+		o.maxResponseSize = size
+		return nil
+	}
+}
+
+// WithStrictDecoding makes service methods fail when a response has fields
+// unknown to this client, allowing to detect changes of the REST API, typically
+// in continuous integration. By default, unknown fields are ignored
+func WithStrictDecoding() ClientOption {
+	return func(o *clientOptions) error {
+		o.strictDecoding = true
+		return nil
+	}
+}
+
+// WithDialTimeout sets the maximum time to wait for a connection to alien4cloud.
+// Defaults to DefaultDialTimeout.
+// This option is ignored when an HTTP client is provided with WithHTTPClient
+func WithDialTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) error {
+		o.dialTimeout = timeout
+		return nil
+	}
+}
+
+// WithDialContext sets the function used to open connections to alien4cloud,
+// allowing to use a custom resolver or to connect to a given address in tests.
+// When set, the WithDialTimeout and WithKeepAlive options are ignored.
+// This option is ignored when an HTTP client is provided with WithHTTPClient
+func WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) ClientOption {
+	return func(o *clientOptions) error {
+		if dial == nil {
+			return errors.New("Dial function option must not be nil")
+		}
+		o.dialContext = dial
+		return nil
+	}
+}
+
+// WithKeepAlive sets the interval between keep-alive probes of connections
+// to alien4cloud. Defaults to DefaultKeepAlive.
+// This option is ignored when an HTTP client is provided with WithHTTPClient
+func WithKeepAlive(interval time.Duration) ClientOption {
+	return func(o *clientOptions) error {
+		o.keepAlive = interval
+		return nil
+	}
+}
+
+// WithTLSHandshakeTimeout sets the maximum time to wait for a TLS handshake
+// with alien4cloud. Defaults to DefaultTLSHandshakeTimeout.
+// This option is ignored when an HTTP client is provided with WithHTTPClient
+func WithTLSHandshakeTimeout(timeout time.Duration) ClientOption {
+	return func(o *clientOptions) error {
+		o.tlsHandshakeTimeout = timeout
+		return nil
+	}
+}
+
+// WithProxy sets the URL of the proxy used to send requests to alien4cloud,
+// whatever the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+// By default, the proxy is defined by these environment variables.
+// This option is ignored when an HTTP client is provided with WithHTTPClient
+func WithProxy(proxyURL string) ClientOption {
+	return func(o *clientOptions) error {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return errors.Wrapf(err, "Malformed proxy URL %s", proxyURL)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return errors.Errorf("Unsupported scheme in proxy URL %s, expected http, https or socks5", proxyURL)
+		}
+		if u.Host == "" {
+			return errors.Errorf("Malformed proxy URL %s, no host defined", proxyURL)
+		}
+		o.proxyURL = u
+		return nil
+	}
+}
+
+// WithNoProxy sets hosts to which requests are sent without proxy.
+// A host matches itself and its subdomains, a leading dot being ignored,
+// and "*" matches all hosts.
+// This option is ignored when an HTTP client is provided with WithHTTPClient
+func WithNoProxy(hosts ...string) ClientOption {
+	return func(o *clientOptions) error {
+		o.noProxy = append(o.noProxy, hosts...)
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header of requests sent to alien4cloud,
+// including login and logout requests. Defaults to DefaultUserAgent
+func WithUserAgent(userAgent string) ClientOption {
+	return func(o *clientOptions) error {
+		o.userAgent = userAgent
+		return nil
+	}
+}
+
+// WithDefaultHeaders sets headers added to every request sent to alien4cloud,
+// including login and logout requests.
+// A header set by a client method on a request, or provided for a call with
+// ContextWithRequestHeaders, overrides a default header having the same key
+func WithDefaultHeaders(headers ...Header) ClientOption {
+	return func(o *clientOptions) error {
+		o.defaultHeaders = append(o.defaultHeaders, headers...)
+		return nil
+	}
+}
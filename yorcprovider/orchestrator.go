@@ -15,7 +15,7 @@
 package yorcprovider
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -30,16 +30,24 @@ type OrchestratorService interface {
 }
 
 type orchestratorService struct {
-	client restClient
+	client restAPI
 }
 
 // GetOrchestrators returns the list of Yorc orchestrators configured
 func (o *orchestratorService) GetOrchestrators() ([]Orchestrator, error) {
+	return o.getOrchestrators(context.Background())
+}
+
+// getOrchestrators returns the list of Yorc orchestrators configured,
+// with a Context that can be canceled
+func (o *orchestratorService) getOrchestrators(ctx context.Context) ([]Orchestrator, error) {
 
 	// Get orchestrator location
-	response, err := o.client.do(
+	response, err := o.client.doWithContext(
+		ctx,
+		"GetOrchestrators",
 		"GET",
-		fmt.Sprintf("%s/orchestrators", yorcProviderRESTPrefix),
+		fmt.Sprintf("%s/orchestrators", o.client.prefix()),
 		nil,
 		[]Header{
 			{
@@ -55,7 +63,11 @@ func (o *orchestratorService) GetOrchestrators() ([]Orchestrator, error) {
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, getError(response.Body)
+		return nil, getError(response)
+	}
+
+	if err = checkJSONResponse(response); err != nil {
+		return nil, err
 	}
 
 	responseBody, err := ioutil.ReadAll(response.Body)
@@ -69,7 +81,7 @@ func (o *orchestratorService) GetOrchestrators() ([]Orchestrator, error) {
 			Orchestrators []Orchestrator `json:"orchestrators,omitempty"`
 		} `json:"data"`
 	}
-	if err = json.Unmarshal([]byte(responseBody), &res); err != nil {
+	if err = o.client.unmarshal(responseBody, &res); err != nil {
 		return nil, errors.Wrapf(err, "Cannot convert the body of response to get the list of orchestrators")
 	}
 
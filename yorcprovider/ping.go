@@ -0,0 +1,63 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Ping checks alien4cloud can be reached and the client authenticated, sending
+// a lightweight request without side effects on the list of orchestrators.
+// It returns nil on success, else an error wrapping a NetworkError when
+// alien4cloud can't be reached, an error matching ErrNotAuthenticated when
+// the login failed, or an APIError for other failures of the server
+func (c *yorcProviderClient) Ping(ctx context.Context) error {
+	response, err := c.client.doWithContext(
+		ctx,
+		"Ping",
+		"GET",
+		fmt.Sprintf("%s/orchestrators", c.client.prefix()),
+		nil,
+		[]Header{
+			{
+				"Content-Type",
+				"application/json",
+			},
+		},
+	)
+
+	if err != nil {
+		return errors.Wrapf(err, "Unable to send request to ping alien4cloud")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return getError(response)
+	}
+
+	if err = checkJSONResponse(response); err != nil {
+		return err
+	}
+
+	// Draining the body to allow the connection to be reused
+	_, err = io.Copy(ioutil.Discard, response.Body)
+	return errors.Wrapf(err, "Unable to read response to ping alien4cloud")
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ErrUnknownPluginVersion is the error returned when the version of the
+// yorc collector plugin can't be retrieved, older versions of the plugin
+// not providing it
+var ErrUnknownPluginVersion = errors.New("unknown plugin version")
+
+// GetPluginVersion returns the version of the yorc collector plugin deployed
+// on alien4cloud. ErrUnknownPluginVersion is returned if the plugin doesn't
+// provide its version
+func (c *yorcProviderClient) GetPluginVersion() (string, error) {
+	return c.GetPluginVersionWithContext(context.Background())
+}
+
+// GetPluginVersionWithContext returns the version of the yorc collector plugin
+// deployed on alien4cloud, with a Context that can be canceled
+func (c *yorcProviderClient) GetPluginVersionWithContext(ctx context.Context) (string, error) {
+	response, err := c.client.doWithContext(
+		ctx,
+		"GetPluginVersion",
+		"GET",
+		fmt.Sprintf("%s/version", c.client.prefix()),
+		nil,
+		[]Header{
+			{
+				"Content-Type",
+				"application/json",
+			},
+		},
+	)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to send request to get the plugin version")
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return "", ErrUnknownPluginVersion
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", getError(response)
+	}
+
+	if err = checkJSONResponse(response); err != nil {
+		return "", err
+	}
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return "", errors.Wrapf(err, "Unable to read response to get the plugin version")
+	}
+
+	var res struct {
+		Data struct {
+			Version string `json:"version"`
+		} `json:"data"`
+	}
+	if err = c.client.unmarshal(responseBody, &res); err != nil {
+		return "", errors.Wrapf(err, "Cannot convert the body of response to get the plugin version")
+	}
+	if res.Data.Version == "" {
+		return "", ErrUnknownPluginVersion
+	}
+
+	return res.Data.Version, nil
+}
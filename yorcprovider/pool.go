@@ -0,0 +1,74 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"sync"
+)
+
+// runPool calls fn on each key using a pool of at most maxInFlight workers,
+// so that no more than maxInFlight calls are in flight at a time.
+// Values returned by calls are stored in results, keyed by key.
+// Once ctx is done, keys not yet processed are not passed to fn, ctx error
+// being their error.
+// A BatchError providing the error per key is returned if any call failed
+func runPool(ctx context.Context, maxInFlight int, keys []string,
+	fn func(ctx context.Context, key string) (interface{}, error), results map[string]interface{}) error {
+
+	if maxInFlight <= 0 {
+		maxInFlight = 1
+	}
+	if maxInFlight > len(keys) {
+		maxInFlight = len(keys)
+	}
+
+	var lock sync.Mutex
+	batchErr := &BatchError{Errors: make(map[string]error)}
+
+	keysChan := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < maxInFlight; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keysChan {
+				var value interface{}
+				err := ctx.Err()
+				if err == nil {
+					value, err = fn(ctx, key)
+				}
+				lock.Lock()
+				if err != nil {
+					batchErr.Errors[key] = err
+				} else {
+					results[key] = value
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		keysChan <- key
+	}
+	close(keysChan)
+	wg.Wait()
+
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+	return nil
+}
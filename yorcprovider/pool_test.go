@@ -0,0 +1,66 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRunPoolLimitsCallsInFlight(t *testing.T) {
+	const limit = 3
+	var keys []string
+	for i := 0; i < 20; i++ {
+		keys = append(keys, strconv.Itoa(i))
+	}
+
+	var lk sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	results := make(map[string]interface{})
+	err := runPool(context.Background(), limit, keys, func(ctx context.Context, key string) (interface{}, error) {
+		lk.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		lk.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		lk.Lock()
+		inFlight--
+		lk.Unlock()
+		if key == "7" {
+			return nil, errors.New("failure")
+		}
+		return key, nil
+	}, results)
+
+	if maxInFlight > limit {
+		t.Errorf("%d calls were in flight at a time, want at most %d", maxInFlight, limit)
+	}
+	if len(results) != len(keys)-1 {
+		t.Errorf("runPool() provided %d results, want %d", len(results), len(keys)-1)
+	}
+	batchErr, ok := err.(*BatchError)
+	if !ok || len(batchErr.Errors) != 1 || batchErr.Errors["7"] == nil {
+		t.Errorf("runPool() error = %v, want a BatchError for key 7", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// proxyFunc returns the function providing the proxy of a request.
+// When proxyURL is nil, the proxy is defined by environment variables.
+// Requests to hosts matching noProxy are sent without proxy
+func proxyFunc(proxyURL *url.URL, noProxy []string) func(*http.Request) (*url.URL, error) {
+	if proxyURL == nil && len(noProxy) == 0 {
+		return http.ProxyFromEnvironment
+	}
+	return func(request *http.Request) (*url.URL, error) {
+		if matchesNoProxy(request.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+		if proxyURL != nil {
+			return proxyURL, nil
+		}
+		return http.ProxyFromEnvironment(request)
+	}
+}
+
+// matchesNoProxy returns true if host matches one of the hosts of noProxy,
+// which matches itself and its subdomains
+func matchesNoProxy(host string, noProxy []string) bool {
+	host = strings.ToLower(host)
+	for _, h := range noProxy {
+		h = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(h), "."))
+		if hostOnly, _, err := net.SplitHostPort(h); err == nil {
+			h = hostOnly
+		}
+		if h == "*" || host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// QueryID identifies a resources usage query. Its string representation
+// <orchestrator>/infra_usage/<collector>/tasks/<id> is the query ID accepted
+// by UsageCollectorService methods
+type QueryID struct {
+	Orchestrator string
+	Collector    string
+	TaskID       string
+}
+
+// String returns the query ID in the form <orchestrator>/infra_usage/<collector>/tasks/<id>
+func (q QueryID) String() string {
+	return q.Orchestrator + "/infra_usage/" + q.Collector + "/tasks/" + q.TaskID
+}
+
+// ParseQueryID parses a query ID of the form <orchestrator>/infra_usage/<collector>/tasks/<id>
+func ParseQueryID(queryID string) (QueryID, error) {
+	values := strings.Split(queryID, "/")
+	if len(values) != 5 || values[1] != "infra_usage" || values[3] != "tasks" ||
+		values[0] == "" || values[2] == "" || values[4] == "" {
+		return QueryID{}, errors.Errorf("Expected a query ID of the form <orchestrator>/infra_usage/<collector>/tasks/<id>, got %s", queryID)
+	}
+	return QueryID{
+		Orchestrator: values[0],
+		Collector:    values[2],
+		TaskID:       values[4],
+	}, nil
+}
+
+// taskID returns the task ID of a query ID, the last segment of this query ID
+func taskID(queryID string) string {
+	return queryID[strings.LastIndex(queryID, "/")+1:]
+}
+
+// taskIDLess returns true if task ID a is lower than task ID b, task IDs
+// being compared as numbers when they are both numbers
+func taskIDLess(a, b string) bool {
+	na, errA := strconv.ParseInt(a, 10, 64)
+	nb, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+	return a < b
+}
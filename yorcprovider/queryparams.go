@@ -0,0 +1,69 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseQueryParams parses query parameters of the form key=value, as
+// provided on a command line, the value being able to contain '='.
+// An error is returned on a malformed parameter or a key defined several times,
+// ParseQueryParamValues supporting repeated keys
+func ParseQueryParams(pairs []string) (map[string]string, error) {
+	params := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, err := parseQueryParam(pair)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := params[key]; ok {
+			return nil, errors.Errorf("Query parameter %s defined several times", key)
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+// ParseQueryParamValues parses query parameters of the form key=value,
+// values of a key defined several times being kept in order.
+// The values returned can be provided to QueryWithValues
+func ParseQueryParamValues(pairs []string) (url.Values, error) {
+	values := make(url.Values, len(pairs))
+	for _, pair := range pairs {
+		key, value, err := parseQueryParam(pair)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = append(values[key], value)
+	}
+	return values, nil
+}
+
+// parseQueryParam parses a query parameter of the form key=value
+func parseQueryParam(pair string) (string, string, error) {
+	i := strings.Index(pair, "=")
+	if i < 0 {
+		return "", "", errors.Errorf("Expected query parameter of the form key=value, got %q", pair)
+	}
+	key := strings.TrimSpace(pair[:i])
+	if key == "" {
+		return "", "", errors.Errorf("Missing key in query parameter %q, expected the form key=value", pair)
+	}
+	return key, pair[i+1:], nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the header providing the ID of a request, allowing to
+// correlate logs of the client, gateways and alien4cloud
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a context providing the ID of requests sent
+// with this context. Requests sent by a call of a client method, including a
+// login done by this call, have the same ID. By default a new ID is
+// generated for each call
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID provided by a context, if any
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok && requestID != ""
+}
+
+// withRequestID returns a context providing a request ID, generating a new
+// one if the context doesn't provide it yet
+func withRequestID(ctx context.Context) context.Context {
+	if _, ok := RequestIDFromContext(ctx); ok {
+		return ctx
+	}
+	return ContextWithRequestID(ctx, newRequestID())
+}
+
+// newRequestID returns a new random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// responseRequestID returns the request ID echoed by the server in a
+// response, or else the ID of the request sent
+func responseRequestID(response *http.Response) string {
+	if requestID := response.Header.Get(RequestIDHeader); requestID != "" {
+		return requestID
+	}
+	if response.Request != nil {
+		return response.Request.Header.Get(RequestIDHeader)
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type maxResponseSizeKey struct{}
+
+// ContextWithMaxResponseSize returns a context changing the maximum size of
+// bodies of responses received with this context, for calls known to return
+// large results, like the collection of a large hosts pool.
+// A size lower or equal to 0 means no limit
+func ContextWithMaxResponseSize(ctx context.Context, size int64) context.Context {
+	return context.WithValue(ctx, maxResponseSizeKey{}, size)
+}
+
+// maxResponseSize returns the maximum size of the body of a response
+// to a request sent with the given context
+func (r *restClient) maxResponseSize(ctx context.Context) int64 {
+	if size, ok := ctx.Value(maxResponseSizeKey{}).(int64); ok {
+		return size
+	}
+	return r.maxRespSize
+}
+
+// limitedBody is a response body failing with ErrResponseTooLarge when
+// more than a maximum size is read
+type limitedBody struct {
+	io.ReadCloser
+	limit     int64
+	remaining int64
+}
+
+// limitBody returns a body failing when more than limit bytes are read,
+// or body itself when limit is lower or equal to 0
+func limitBody(body io.ReadCloser, limit int64) io.ReadCloser {
+	if limit <= 0 {
+		return body
+	}
+	return &limitedBody{ReadCloser: body, limit: limit, remaining: limit}
+}
+
+// Read reads the body, failing once the maximum size is exceeded
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Checking if the body provides more data than allowed
+		var extra [1]byte
+		n, err := b.ReadCloser.Read(extra[:])
+		if n > 0 {
+			return 0, errors.Wrapf(ErrResponseTooLarge, "Response body exceeds the maximum size of %d bytes", b.limit)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
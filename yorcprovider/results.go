@@ -0,0 +1,177 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// UnmarshalResults decodes the results of a usage collection into v,
+// which is typically a pointer to a collector specific structure like SlurmUsage,
+// GoogleUsage or AWSUsage, or to a structure defined by the caller for collectors
+// this package doesn't provide a structure for. Results are re-encoded in JSON and decoded into v,
+// so JSON tags of v are honored and nested structures are preserved.
+// Fields absent from results are left untouched in v, and Results is not modified.
+//
+// For example, to decode the results of a Slurm usage collection:
+//
+//	var usage yorcprovider.SlurmUsage
+//	if err := collection.UnmarshalResults(&usage); err != nil {
+//		return err
+//	}
+//	fmt.Printf("%d/%d nodes allocated\n", usage.NodesAllocated, usage.NodesTotal)
+//
+// or to decode results into a type defined locally:
+//
+//	type quota struct {
+//		Project string `json:"project"`
+//		Limits  struct {
+//			CPUs int `json:"cpus"`
+//		} `json:"limits"`
+//	}
+//	var usage struct {
+//		Quotas []quota `json:"quotas"`
+//	}
+//	if err := collection.UnmarshalResults(&usage); err != nil {
+//		return err
+//	}
+func (u *UsageCollection) UnmarshalResults(v interface{}) error {
+	b, err := json.Marshal(u.Results)
+	if err != nil {
+		return errors.Wrapf(err, "Cannot encode usage collection results")
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		return errors.Wrapf(err, "Cannot decode usage collection results")
+	}
+	return nil
+}
+
+// HostsPoolUsage decodes the results of a Hosts Pool usage collection.
+// Results provide either a list of hosts in a hosts field, or an object
+// per host, keyed by host name, in which case hosts are sorted by name
+func (u *UsageCollection) HostsPoolUsage() (*HostsPoolUsage, error) {
+	var usage HostsPoolUsage
+	if _, ok := u.Results["hosts"]; ok {
+		if err := u.UnmarshalResults(&usage); err != nil {
+			return nil, err
+		}
+		return &usage, nil
+	}
+
+	var hosts map[string]HostsPoolHost
+	if err := u.UnmarshalResults(&hosts); err != nil {
+		return nil, err
+	}
+	for name, host := range hosts {
+		if host.Name == "" {
+			host.Name = name
+		}
+		usage.Hosts = append(usage.Hosts, host)
+	}
+	sort.Slice(usage.Hosts, func(i, j int) bool {
+		return usage.Hosts[i].Name < usage.Hosts[j].Name
+	})
+	return &usage, nil
+}
+
+// Save writes the usage collection in JSON to w, allowing to reload it
+// later with LoadUsageCollection, for offline analysis or as a test fixture
+func (u *UsageCollection) Save(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(u); err != nil {
+		return errors.Wrapf(err, "Cannot save usage collection %s", u.ID)
+	}
+	return nil
+}
+
+// FormatResults writes the results of the usage collection to w as JSON
+// indented with indent. Keys of objects are sorted at all levels,
+// providing an output that can be compared between collections
+func (u *UsageCollection) FormatResults(w io.Writer, indent string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	if err := encoder.Encode(u.Results); err != nil {
+		return errors.Wrapf(err, "Cannot format results of usage collection %s", u.ID)
+	}
+	return nil
+}
+
+// Pretty returns the results of the usage collection as JSON indented with
+// two spaces, keys being sorted. An empty string is returned if results
+// can't be encoded
+func (u *UsageCollection) Pretty() string {
+	var b strings.Builder
+	if err := u.FormatResults(&b, "  "); err != nil {
+		return ""
+	}
+	return b.String()
+}
+
+// LoadUsageCollection reads a usage collection previously written by Save.
+// Progress is -1 if it was not saved
+func LoadUsageCollection(r io.Reader) (*UsageCollection, error) {
+	// Save writes the progress as is, an unknown progress being -1
+	collection := &UsageCollection{Progress: -1}
+	if err := json.NewDecoder(r).Decode(collection); err != nil {
+		return nil, errors.Wrapf(err, "Cannot load usage collection")
+	}
+	return collection, nil
+}
+
+// usageLine is a line written by WriteJSONLines
+type usageLine struct {
+	ID       string      `json:"id,omitempty"`
+	TargetID string      `json:"target_id,omitempty"`
+	Type     string      `json:"type,omitempty"`
+	Status   QueryStatus `json:"status,omitempty"`
+	Resource string      `json:"resource"`
+	Value    interface{} `json:"value"`
+}
+
+// WriteJSONLines writes the results of the usage collection to w as
+// newline-delimited JSON, one object per top-level key of the results,
+// sorted by key. Each object provides the ID, target, type and status of the
+// collection, the key in a resource field, and its value in a value field,
+// nested values being kept as is
+func (u *UsageCollection) WriteJSONLines(w io.Writer) error {
+	var keys []string
+	for k := range u.Results {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	encoder := json.NewEncoder(w)
+	for _, k := range keys {
+		line := usageLine{
+			ID:       u.ID,
+			TargetID: u.TargetID,
+			Type:     u.Type,
+			Status:   u.QueryStatus(),
+			Resource: k,
+			Value:    u.Results[k],
+		}
+		if err := encoder.Encode(line); err != nil {
+			return errors.Wrapf(err, "Cannot write usage %s of collection %s", k, u.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveAndLoadUsageCollection(t *testing.T) {
+	for _, progress := range []int{-1, 0, 37, 100} {
+		collection := &UsageCollection{
+			ID:       "1",
+			TargetID: "slurm-hpc",
+			Type:     "slurm",
+			Status:   QueryStatusRunning,
+			Results:  map[string]interface{}{"nodes_total": float64(12)},
+			Progress: progress,
+		}
+		var buf bytes.Buffer
+		if err := collection.Save(&buf); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		loaded, err := LoadUsageCollection(&buf)
+		if err != nil {
+			t.Fatalf("LoadUsageCollection() error = %v", err)
+		}
+		if !reflect.DeepEqual(loaded, collection) {
+			t.Errorf("LoadUsageCollection() = %+v, want %+v", loaded, collection)
+		}
+	}
+}
+
+func TestLoadUsageCollectionWithoutProgress(t *testing.T) {
+	collection, err := LoadUsageCollection(strings.NewReader(`{"id": "1", "status": "DONE"}`))
+	if err != nil {
+		t.Fatalf("LoadUsageCollection() error = %v", err)
+	}
+	if collection.Progress != -1 {
+		t.Errorf("LoadUsageCollection() Progress = %d, want -1", collection.Progress)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxRetryDelay is the maximum delay before sending a request again
+const maxRetryDelay = 30 * time.Second
+
+// IsRetryable returns true if a request failing with this error is worth sending
+// again: true for an APIError having a 429 or 5xx status and for a transient
+// NetworkError, false for other errors like 4xx statuses or a canceled context.
+// The client is more conservative when retries are enabled with WithRetry: it
+// doesn't send a request again on a 500 or 501 status, which are rarely
+// transient, nor on a 502 or 504 status for requests which are not idempotent,
+// like the submission of a query, as such a request may have been processed
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return isRetryableStatus(apiErr.StatusCode)
+	}
+	var netErr *NetworkError
+	if errors.As(err, &netErr) {
+		return isTransientNetworkError(netErr.Err, isIdempotent(netErr.Method))
+	}
+	return false
+}
+
+// shouldRetry returns true if a request failing with this response or error
+// can be sent again
+func shouldRetry(method string, response *http.Response, err error) bool {
+	if err != nil {
+		return isTransientNetworkError(err, isIdempotent(method))
+	}
+	return isTransientStatus(response.StatusCode, isIdempotent(method))
+}
+
+// isRetryableStatus returns true if the status code of a response may be due
+// to a transient condition: 429 or 5xx
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// isTransientStatus returns true if the status code of a response is due
+// to a transient condition, the request being worth sending again by the client.
+// Statuses 429 and 503 mean the request was not processed, so any request can
+// be sent again, while with a gateway error, a request which is not idempotent
+// may have been processed
+func isTransientStatus(statusCode int, idempotent bool) bool {
+	if !isRetryableStatus(statusCode) {
+		return false
+	}
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// isIdempotent returns true if a request with this method can be sent again
+// without side effect, even if it was already received by the server
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isTransientNetworkError returns true if a network error is transient.
+// Errors which can occur once the request was received by the server, like
+// a connection reset, are considered as transient only for idempotent requests
+func isTransientNetworkError(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrClientClosed) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+
+	// The request was not sent when the connection couldn't be established
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	if !idempotent {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryDelay returns the delay before sending a request again, after
+// the given attempt
+func (r *restClient) retryDelay(attempt int, response *http.Response) time.Duration {
+	if response != nil {
+		if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay := time.Duration(seconds) * time.Second
+			if delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+			return delay
+		}
+	}
+
+	delay := r.retryBackoff
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
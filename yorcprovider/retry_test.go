@@ -0,0 +1,179 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRetryStatusCodes(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		statusCode   int
+		wantRequests int
+	}{
+		{"GET on 503", http.MethodGet, http.StatusServiceUnavailable, 2},
+		{"GET on 502", http.MethodGet, http.StatusBadGateway, 2},
+		{"GET on 504", http.MethodGet, http.StatusGatewayTimeout, 2},
+		{"GET on 429", http.MethodGet, http.StatusTooManyRequests, 2},
+		{"GET on 500", http.MethodGet, http.StatusInternalServerError, 1},
+		{"POST on 503", http.MethodPost, http.StatusServiceUnavailable, 2},
+		{"POST on 429", http.MethodPost, http.StatusTooManyRequests, 2},
+		{"POST on 502", http.MethodPost, http.StatusBadGateway, 1},
+		{"POST on 500", http.MethodPost, http.StatusInternalServerError, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lk sync.Mutex
+			requests := 0
+			client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lk.Lock()
+				requests++
+				first := requests == 1
+				lk.Unlock()
+				switch {
+				case first:
+					w.WriteHeader(tt.statusCode)
+				case r.Method == http.MethodPost:
+					w.Header().Set("Location", DefaultRESTPrefix+"/orchestrators/yorc/infra_usage/slurm/tasks/1")
+					w.WriteHeader(http.StatusCreated)
+				default:
+					writeTestData(t, w, http.StatusOK, map[string]interface{}{
+						"orchestrators": []Orchestrator{{Name: "yorc"}},
+					})
+				}
+			}), WithRetry(3, time.Millisecond))
+			defer server.Close()
+
+			var err error
+			if tt.method == http.MethodPost {
+				_, err = client.UsageCollectorService().Query("yorc", "slurm", "hpc", nil)
+			} else {
+				_, err = client.OrchestratorService().GetOrchestrators()
+			}
+			if tt.wantRequests > 1 && err != nil {
+				t.Errorf("Request error = %v, want a success once sent again", err)
+			}
+			if tt.wantRequests == 1 && err == nil {
+				t.Errorf("Request succeeded, want the error of the first response")
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("Server received %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestRetryDialFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeTestData(t, w, http.StatusOK, map[string]interface{}{
+			"orchestrators": []Orchestrator{{Name: "yorc"}},
+		})
+	}))
+	defer server.Close()
+
+	var lk sync.Mutex
+	dials := 0
+	dialer := &net.Dialer{}
+	client, err := New(server.URL, WithRetry(3, time.Millisecond),
+		WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			lk.Lock()
+			dials++
+			first := dials == 1
+			lk.Unlock()
+			if first {
+				return nil, &net.OpError{Op: "dial", Net: network, Err: &net.AddrError{Err: "connection refused", Addr: addr}}
+			}
+			return dialer.DialContext(ctx, network, addr)
+		}))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err = client.OrchestratorService().GetOrchestrators(); err != nil {
+		t.Fatalf("GetOrchestrators() error = %v, want a success once connected", err)
+	}
+	if dials != 2 {
+		t.Errorf("Dialed %d times, want 2", dials)
+	}
+}
+
+func TestRetryBudgetAfterLogin(t *testing.T) {
+	const maxRetries = 3
+	var lk sync.Mutex
+	logins, requests := 0, 0
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lk.Lock()
+		defer lk.Unlock()
+		if r.URL.Path == "/login" {
+			logins++
+			return
+		}
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}), WithCredentials("user", "password"), WithRetry(maxRetries, time.Millisecond))
+	defer server.Close()
+
+	if _, err := client.OrchestratorService().GetOrchestrators(); err == nil {
+		t.Fatal("GetOrchestrators() succeeded, want an error")
+	}
+	// The request is sent again once after the login, then retried maxRetries times
+	if want := maxRetries + 2; requests != want {
+		t.Errorf("Server received %d requests, want %d", requests, want)
+	}
+	if logins != 1 {
+		t.Errorf("Server received %d login requests, want 1", logins)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&APIError{StatusCode: http.StatusBadRequest}, false},
+		{&APIError{StatusCode: http.StatusNotFound}, false},
+		{&APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{&APIError{StatusCode: http.StatusInternalServerError}, true},
+		{&APIError{StatusCode: http.StatusNotImplemented}, true},
+		{&APIError{StatusCode: http.StatusBadGateway}, true},
+		{&APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{errors.Wrap(&APIError{StatusCode: http.StatusGatewayTimeout}, "Query failed"), true},
+		{&NetworkError{Method: http.MethodPost, Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, true},
+		{&NetworkError{Method: http.MethodGet, Err: syscall.ECONNRESET}, true},
+		{&NetworkError{Method: http.MethodPost, Err: syscall.ECONNRESET}, false},
+		{errors.Wrap(context.Canceled, "Query failed"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"sync"
+)
+
+// session tracks logins to alien4cloud, serializing them so that concurrent
+// requests needing a login trigger a single one
+type session struct {
+	lk sync.Mutex
+	// generation is incremented on each successful login
+	generation uint64
+}
+
+// current returns the generation of the current session
+func (s *session) current() uint64 {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	return s.generation
+}
+
+// login logs in to alien4cloud
+func (r *restClient) login(ctx context.Context) error {
+	r.session.lk.Lock()
+	defer r.session.lk.Unlock()
+	return r.loginLocked(ctx)
+}
+
+// relogin logs in again to alien4cloud, unless another login was done since
+// the session generation seen by the caller
+func (r *restClient) relogin(ctx context.Context, seen uint64) error {
+	r.session.lk.Lock()
+	defer r.session.lk.Unlock()
+	if r.session.generation != seen {
+		// Another request already logged in again
+		return nil
+	}
+	return r.loginLocked(ctx)
+}
+
+// ensureSession logs in to alien4cloud when auto-login is enabled and
+// no session exists yet. It returns true if a login was done
+func (r *restClient) ensureSession(ctx context.Context) (bool, error) {
+	if !r.autoLogin || r.basicAuth {
+		return false, nil
+	}
+	r.session.lk.Lock()
+	defer r.session.lk.Unlock()
+	if r.session.generation > 0 || r.hasSession() {
+		return false, nil
+	}
+	return true, r.loginLocked(ctx)
+}
+
+// loginLocked logs in to alien4cloud, the session lock being held
+func (r *restClient) loginLocked(ctx context.Context) error {
+	if err := r.doLogin(ctx); err != nil {
+		return err
+	}
+	r.session.generation++
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"sync"
+)
+
+// queryTracker keeps track of queries submitted by the client which are not
+// over yet, allowing to cancel them on shutdown.
+// At most max queries are tracked, the oldest ones being forgotten first.
+// A nil tracker tracks nothing
+type queryTracker struct {
+	lk      sync.Mutex
+	max     int
+	queries []string
+}
+
+func newQueryTracker(max int) *queryTracker {
+	return &queryTracker{max: max}
+}
+
+// add tracks a query
+func (t *queryTracker) add(queryID string) {
+	if t == nil {
+		return
+	}
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	if len(t.queries) >= t.max {
+		t.queries = t.queries[1:]
+	}
+	t.queries = append(t.queries, queryID)
+}
+
+// remove stops tracking a query
+func (t *queryTracker) remove(queryID string) {
+	if t == nil {
+		return
+	}
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	for i, id := range t.queries {
+		if id == queryID {
+			t.queries = append(t.queries[:i], t.queries[i+1:]...)
+			return
+		}
+	}
+}
+
+// list returns the queries tracked
+func (t *queryTracker) list() []string {
+	if t == nil {
+		return nil
+	}
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	return append([]string(nil), t.queries...)
+}
+
+// CancelAll cancels the queries submitted by the client which are not over yet,
+// when queries are tracked, as enabled by WithQueryTracking.
+// A failure to cancel a query doesn't prevent other queries from being canceled,
+// a BatchError providing the error per query ID being returned
+func (u *usageCollectorService) CancelAll(ctx context.Context) error {
+	batchErr := &BatchError{Errors: make(map[string]error)}
+	for _, queryID := range u.tracker.list() {
+		if err := u.CancelQueryWithContext(ctx, queryID); err != nil {
+			batchErr.Errors[queryID] = err
+		}
+	}
+	if len(batchErr.Errors) > 0 {
+		return batchErr
+	}
+	return nil
+}
@@ -15,12 +15,15 @@
 package yorcprovider
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strings"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -29,28 +32,249 @@ import (
 type UsageCollectorService interface {
 	// Returns the list of usage collectors provided on a given orchestrator
 	GetUsageCollectors(orchestratorName string) ([]UsageCollector, error)
+	// Returns usage collectors provided on all orchestrators, keyed by orchestrator name
+	GetAllUsageCollectors(ctx context.Context) (map[string][]UsageCollector, error)
+	// Returns the usage collectors provided on a given orchestrator, along with the parameters they accept
+	GetCollectorCapabilities(orchestratorName string) ([]CollectorCapability, error)
+	// Returns the usage collector having a given ID on a given orchestrator
+	GetUsageCollector(orchestratorName, collectorID string) (*UsageCollector, error)
+	// Returns the usage collector having a given ID on a given orchestrator with a Context that can be canceled
+	GetUsageCollectorWithContext(ctx context.Context, orchestratorName, collectorID string) (*UsageCollector, error)
+	// Returns the usage collector having a given ID and provided by a given origin on a given orchestrator
+	GetUsageCollectorByOrigin(orchestratorName, collectorID, origin string) (*UsageCollector, error)
 	// Queries the collection of resources usage on a given location
-	// The ID of a query that will perform the collection is returned
-	Query(orchestratorName, collectorID, location string, queryParameters map[string]string) (string, error)
+	// The ID of a query that will perform the collection is returned as a QueryID
+	Query(orchestratorName, collectorID, location string, queryParameters map[string]string) (QueryID, error)
+	// Queries the collection of resources usage on a given location
+	// The ID of a query that will perform the collection is returned as a string,
+	// as returned by Query in previous versions
+	LegacyQuery(orchestratorName, collectorID, location string, queryParameters map[string]string) (string, error)
+	// Queries the collection of resources usage on a given location with a Context that can be canceled
+	// The ID of the query is returned as a QueryID
+	SubmitQuery(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string) (QueryID, error)
+	// Queries the collection of resources usage on a given location with a Context that can be canceled
+	QueryWithContext(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string) (string, error)
+	// Queries the collection of resources usage on a given location with a Context that can be canceled
+	// The ID of the query is returned along with a function canceling the query
+	QueryWithCancel(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string) (string, func() error, error)
+	// Queries the collection of resources usage on a given location with a Context that can be canceled,
+	// providing the status and headers of the response in meta
+	QueryWithMeta(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string, meta *ResponseMeta) (string, error)
+	// Queries the collection of resources usage on a given location with a Context that can be canceled
+	// The ID of the query is returned along with its href
+	QueryReturningHRef(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string) (string, string, error)
+	// Queries the collection of resources usage on a given location, with query parameters
+	// which can have several values
+	QueryWithValues(ctx context.Context, orchestratorName, collectorID, location string, queryParameters url.Values) (string, error)
+	// Queries the collection of resources usage on a given location, providing parameters in a JSON body
+	QueryWithBody(ctx context.Context, orchestratorName, collectorID, location string, body interface{}) (string, error)
+	// Queries the collection of resources usage on several locations concurrently,
+	// waits for the end of these collections and returns collected usage per location
+	QueryAll(ctx context.Context, orchestratorName, collectorID string, locations []string, queryParameters map[string]string) (map[string]*UsageCollection, error)
+	// Queries the collection of resources usage on a location of several orchestrators,
+	// waits for the end of these collections and returns their merged results
+	GetAggregatedUsage(ctx context.Context, collectorID, location string, orchestrators []string) (map[string]interface{}, error)
 	// Deletes a query of resources usage collection
 	DeleteQuery(queryID string) error
-	// Gets queries of resources usage performed on a given orchestrator, for a given collector
+	// Deletes a query of resources usage collection identified by a QueryID
+	DeleteQueryByID(ctx context.Context, queryID QueryID) error
+	// Deletes a query of resources usage collection if it is over, returns true if it was deleted
+	DeleteQueryIfTerminal(ctx context.Context, queryID string) (bool, error)
+	// Deletes queries of resources usage collection which are over, performed on a given
+	// orchestrator for a given collector, and returns the number of queries deleted
+	DeleteTerminalQueries(ctx context.Context, orchestratorName, collectorID string) (int, error)
+	// Deletes queries of resources usage collection older than age, performed on a given
+	// orchestrator for a given collector, and returns the number of queries deleted.
+	// As the REST API doesn't expose the creation time of queries, queries which are
+	// over are deleted whatever their age
+	DeleteQueriesOlderThan(ctx context.Context, orchestratorName, collectorID string, age time.Duration) (int, error)
+	// Cancels a query of resources usage collection, the query is kept with the status canceled
+	CancelQuery(queryID string) error
+	// Cancels a query of resources usage collection with a Context that can be canceled
+	CancelQueryWithContext(ctx context.Context, queryID string) error
+	// Cancels queries submitted by this client which are not over yet
+	CancelAll(ctx context.Context) error
+	// Gets IDs of queries of resources usage performed on a given orchestrator, for a given collector
 	GetQueryIDs(orchestratorName, collectorID string) ([]string, error)
+	// Gets queries of resources usage performed on a given orchestrator, for a given collector
+	GetQueries(orchestratorName, collectorID string) ([]QueryInfo, error)
+	// Gets queries of resources usage performed on a given orchestrator, for a given collector,
+	// along with their status
+	GetQueriesWithStatus(ctx context.Context, orchestratorName, collectorID string) ([]QueryInfo, error)
+	// Gets queries of resources usage performed on a given orchestrator, for a given collector, newest first
+	GetQueriesSorted(orchestratorName, collectorID string) ([]QueryInfo, error)
+	// Gets a page of queries of resources usage performed on a given orchestrator, for a given collector,
+	// newest first, along with the total number of queries
+	GetQueriesPage(ctx context.Context, orchestratorName, collectorID string, offset, limit int) ([]QueryInfo, int, error)
+	// Gets queries of resources usage performed on a given orchestrator, for a given collector,
+	// which have a given status
+	GetQueriesByStatus(ctx context.Context, orchestratorName, collectorID string, status QueryStatus) ([]QueryInfo, error)
 	// Gets results of a resources usage collection query
 	GetCollectedUsage(queryID string) (*UsageCollection, error)
+	// Gets results of a resources usage collection query with a Context that can be canceled
+	GetCollectedUsageWithContext(ctx context.Context, queryID string) (*UsageCollection, error)
+	// Gets results of a resources usage collection query identified by a QueryID
+	GetCollectedUsageByID(ctx context.Context, queryID QueryID) (*UsageCollection, error)
+	// Gets results of a resources usage collection query with a Context that can be canceled,
+	// providing the status and headers of the response in meta
+	GetCollectedUsageWithMeta(ctx context.Context, queryID string, meta *ResponseMeta) (*UsageCollection, error)
+	// Streams results of a resources usage collection query, calling handler on each result entry
+	StreamCollectedUsage(ctx context.Context, queryID string, handler func(key string, value json.RawMessage) error) error
+	// Gets the status of a resources usage collection query
+	GetQueryStatus(queryID string) (QueryStatus, error)
+	// Gets the status of a resources usage collection query with a Context that can be canceled
+	GetQueryStatusWithContext(ctx context.Context, queryID string) (QueryStatus, error)
+	// Waits for the end of a resources usage collection query and returns its results
+	WaitForQueryCompletion(ctx context.Context, queryID string, pollInterval time.Duration) (*UsageCollection, error)
+	// Waits for the end of a resources usage collection query, calling onStatus on each change
+	// of status or progress, and returns its results
+	WaitForQueryCompletionFunc(ctx context.Context, queryID string, pollInterval time.Duration, onStatus func(status QueryStatus, progress int)) (*UsageCollection, error)
 }
 
+// defaultPollInterval is the interval between two polls of a query status
+// in helpers waiting for the end of a query
+const defaultPollInterval = time.Second
+
 type usageCollectorService struct {
-	client restClient
+	client restAPI
+	// tracker tracks queries not yet over, when enabled
+	tracker *queryTracker
+	// validateQueries enables the validation of queries before submitting them
+	validateQueries bool
+	// defaultQueryParams are query parameters provided to all queries
+	defaultQueryParams url.Values
+	// cleanupQueries enables the deletion of queries done by batch operations
+	cleanupQueries bool
 }
 
 // GetUsageCollectors returns the list of usage collectors provided on a given orchestrator
 func (u *usageCollectorService) GetUsageCollectors(orchestratorName string) ([]UsageCollector, error) {
+	return u.getUsageCollectors(context.Background(), orchestratorName)
+}
+
+// GetAllUsageCollectors returns usage collectors provided on all orchestrators,
+// keyed by orchestrator name. Collectors of orchestrators are requested
+// concurrently, the number of concurrent requests being limited by the client
+// maximum concurrency.
+// A failure on an orchestrator doesn't prevent collectors of other orchestrators
+// from being returned, along with a BatchError providing the error per orchestrator
+func (u *usageCollectorService) GetAllUsageCollectors(ctx context.Context) (map[string][]UsageCollector, error) {
+	orchestrators, err := (&orchestratorService{u.client}).getOrchestrators(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(orchestrators))
+	for _, orchestrator := range orchestrators {
+		names = append(names, orchestrator.Name)
+	}
+
+	collectors := make(map[string]interface{})
+	err = u.runConcurrently(ctx, names, func(ctx context.Context, orchestratorName string) (interface{}, error) {
+		return u.getUsageCollectors(ctx, orchestratorName)
+	}, collectors)
+
+	results := make(map[string][]UsageCollector)
+	for orchestratorName, c := range collectors {
+		results[orchestratorName] = c.([]UsageCollector)
+	}
+	return results, err
+}
+
+// GetUsageCollector returns the usage collector having a given ID on a given orchestrator.
+// A CollectorNotFoundError matching ErrCollectorNotFound is returned if there is
+// no such collector, and an error is returned if collectors from several origins
+// have this ID
+func (u *usageCollectorService) GetUsageCollector(orchestratorName, collectorID string) (*UsageCollector, error) {
+	return u.GetUsageCollectorWithContext(context.Background(), orchestratorName, collectorID)
+}
+
+// GetUsageCollectorWithContext returns the usage collector having a given ID
+// on a given orchestrator with a Context that can be canceled.
+// A CollectorNotFoundError is returned if there is no such collector
+func (u *usageCollectorService) GetUsageCollectorWithContext(ctx context.Context, orchestratorName, collectorID string) (*UsageCollector, error) {
+	return u.findUsageCollector(ctx, orchestratorName, collectorID, "")
+}
+
+// GetUsageCollectorByOrigin returns the usage collector having a given ID,
+// provided by a given origin plugin, on a given orchestrator.
+// A CollectorNotFoundError is returned if there is no such collector
+func (u *usageCollectorService) GetUsageCollectorByOrigin(orchestratorName, collectorID, origin string) (*UsageCollector, error) {
+	return u.findUsageCollector(context.Background(), orchestratorName, collectorID, origin)
+}
+
+// findUsageCollector returns the usage collector having a given ID on a given
+// orchestrator, provided by a given origin unless origin is empty.
+// An error is returned if no origin is specified and several collectors
+// from different origins have this ID
+func (u *usageCollectorService) findUsageCollector(ctx context.Context, orchestratorName, collectorID, origin string) (*UsageCollector, error) {
+	collectors, err := u.getUsageCollectors(ctx, orchestratorName)
+	if err != nil {
+		return nil, err
+	}
+
+	var available []string
+	var matching []UsageCollector
+	for _, collector := range collectors {
+		if collector.ID == collectorID && (origin == "" || collector.Origin == origin) {
+			matching = append(matching, collector)
+		}
+		available = append(available, collector.ID)
+	}
+
+	switch len(matching) {
+	case 0:
+		return nil, &CollectorNotFoundError{
+			Orchestrator: orchestratorName,
+			CollectorID:  collectorID,
+			Origin:       origin,
+			Available:    available,
+		}
+	case 1:
+		return &matching[0], nil
+	}
+
+	var origins []string
+	for _, collector := range matching {
+		origins = append(origins, collector.Origin)
+	}
+	return nil, errors.Errorf("Several collectors %s found on orchestrator %s, provided by %v, an origin must be specified",
+		collectorID, orchestratorName, origins)
+}
+
+// getUsageCollectors returns the list of usage collectors provided on a given orchestrator
+func (u *usageCollectorService) getUsageCollectors(ctx context.Context, orchestratorName string) ([]UsageCollector, error) {
+	capabilities, err := u.getCollectorCapabilities(ctx, orchestratorName)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []UsageCollector
+	for _, c := range capabilities {
+		result = append(result, UsageCollector{ID: c.ID, Origin: c.Origin})
+	}
+	return result, nil
+}
+
+// GetCollectorCapabilities returns the usage collectors provided on a given
+// orchestrator, along with the query parameters they accept.
+// Parameters are provided only when the plugin registry exposes them, which is
+// not the case of all versions of the plugin: an empty list of parameters
+// means that parameters are unknown, not that no parameter is accepted
+func (u *usageCollectorService) GetCollectorCapabilities(orchestratorName string) ([]CollectorCapability, error) {
+	return u.getCollectorCapabilities(context.Background(), orchestratorName)
+}
+
+// getCollectorCapabilities returns the registry of usage collectors provided
+// on a given orchestrator
+func (u *usageCollectorService) getCollectorCapabilities(ctx context.Context, orchestratorName string) ([]CollectorCapability, error) {
 
 	// Get orchestrator location
-	response, err := u.client.do(
+	response, err := u.client.doWithContext(
+		ctx,
+		"GetUsageCollectors",
 		"GET",
-		fmt.Sprintf("%s/orchestrators/%s/registry/infra_usage_collectors", yorcProviderRESTPrefix, orchestratorName),
+		orchestratorPath(u.client.prefix(), orchestratorName)+"/registry/infra_usage_collectors",
 		nil,
 		[]Header{
 			{
@@ -66,7 +290,11 @@ func (u *usageCollectorService) GetUsageCollectors(orchestratorName string) ([]U
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, getError(response.Body)
+		return nil, getNotFoundError(response, ErrOrchestratorNotFound)
+	}
+
+	if err = checkJSONResponse(response); err != nil {
+		return nil, err
 	}
 
 	responseBody, err := ioutil.ReadAll(response.Body)
@@ -77,38 +305,166 @@ func (u *usageCollectorService) GetUsageCollectors(orchestratorName string) ([]U
 
 	var res struct {
 		Data struct {
-			Infrastructures []UsageCollector `json:"infrastructure_usage_collectors,omitempty"`
+			Infrastructures []CollectorCapability `json:"infrastructure_usage_collectors,omitempty"`
 		} `json:"data"`
 	}
-	if err = json.Unmarshal([]byte(responseBody), &res); err != nil {
+	if err = u.client.unmarshal(responseBody, &res); err != nil {
 		return nil, errors.Wrapf(err, "Cannot convert the body of response to get collectors on %s", orchestratorName)
 	}
 
 	return res.Data.Infrastructures, err
 }
 
-// Queries the collection of resources usage on a given location
+// Query queries the collection of resources usage on a given location.
+// Query parameters are sent sorted by key, the URL of a query being the same
+// for the same parameters.
+// The ID of a query that will perform the collection is returned as a QueryID
+func (u *usageCollectorService) Query(orchestratorName, collectorID, location string, queryParameters map[string]string) (QueryID, error) {
+	return u.SubmitQuery(context.Background(), orchestratorName, collectorID, location, queryParameters)
+}
+
+// LegacyQuery queries the collection of resources usage on a given location.
+// The ID of a query that will perform the collection is returned as a string,
+// as returned by Query in previous versions.
+//
+// Deprecated: use Query, returning a QueryID
+func (u *usageCollectorService) LegacyQuery(orchestratorName, collectorID, location string, queryParameters map[string]string) (string, error) {
+	return u.QueryWithContext(context.Background(), orchestratorName, collectorID, location, queryParameters)
+}
+
+// QueryWithContext queries the collection of resources usage on a given location
+// with a Context that can be canceled.
 // The ID of a query that will perform the collection is returned
-func (u *usageCollectorService) Query(orchestratorName, collectorID, location string, queryParameters map[string]string) (string, error) {
+func (u *usageCollectorService) QueryWithContext(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string) (string, error) {
 
-	var queryID string
-	usageURL, err := url.Parse(fmt.Sprintf("%s/orchestrators/%s/infra_usage/%s/%s",
-		yorcProviderRESTPrefix, orchestratorName, collectorID, location))
+	return u.QueryWithMeta(ctx, orchestratorName, collectorID, location, queryParameters, nil)
+}
+
+// QueryWithCancel queries the collection of resources usage on a given location
+// with a Context that can be canceled.
+// The ID of the query is returned along with a function canceling this query.
+// This function can be called several times, only the first call canceling
+// the query and returning the error, if any. It doesn't depend on ctx, so that
+// a query can still be canceled once ctx is done
+func (u *usageCollectorService) QueryWithCancel(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string) (string, func() error, error) {
+	queryID, err := u.QueryWithContext(ctx, orchestratorName, collectorID, location, queryParameters)
 	if err != nil {
-		return queryID, err
+		return queryID, nil, err
 	}
 
-	query := usageURL.Query()
+	var once sync.Once
+	cancel := func() error {
+		var err error
+		once.Do(func() {
+			err = u.CancelQuery(queryID)
+		})
+		return err
+	}
+	return queryID, cancel, nil
+}
+
+// QueryWithMeta queries the collection of resources usage on a given location
+// with a Context that can be canceled, storing the status and headers of the
+// response in meta.
+// The ID of a query that will perform the collection is returned
+func (u *usageCollectorService) QueryWithMeta(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string, meta *ResponseMeta) (string, error) {
+
+	query := url.Values{}
 	for k, v := range queryParameters {
 		query.Set(k, v)
 	}
 
+	return u.submitQuery(ctx, "Query", orchestratorName, collectorID, location, query, nil, meta)
+}
+
+// QueryReturningHRef queries the collection of resources usage on a given location
+// with a Context that can be canceled.
+// The ID of a query that will perform the collection is returned, along with
+// the href of this query, as provided by the REST API, from which the ID is extracted
+func (u *usageCollectorService) QueryReturningHRef(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string) (string, string, error) {
+	var meta ResponseMeta
+	queryID, err := u.QueryWithMeta(ctx, orchestratorName, collectorID, location, queryParameters, &meta)
+	if err != nil {
+		return queryID, "", err
+	}
+	return queryID, meta.Header.Get("Location"), nil
+}
+
+// QueryWithValues queries the collection of resources usage on a given location
+// with a Context that can be canceled, providing query parameters which can
+// have several values, like resource=cpu&resource=mem.
+// The ID of a query that will perform the collection is returned
+func (u *usageCollectorService) QueryWithValues(ctx context.Context, orchestratorName, collectorID, location string, queryParameters url.Values) (string, error) {
+	return u.submitQuery(ctx, "Query", orchestratorName, collectorID, location, queryParameters, nil, nil)
+}
+
+// SubmitQuery queries the collection of resources usage on a given location
+// with a Context that can be canceled.
+// The ID of the query created is returned as a QueryID, its String() method
+// providing the ID expected by other methods of this service.
+// If the ID of the query created can't be parsed, the error provides this ID
+func (u *usageCollectorService) SubmitQuery(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string) (QueryID, error) {
+	queryID, err := u.QueryWithContext(ctx, orchestratorName, collectorID, location, queryParameters)
+	if err != nil {
+		return QueryID{}, err
+	}
+	id, err := ParseQueryID(queryID)
+	if err != nil {
+		// The query was created, its ID being provided to allow to manage it
+		return QueryID{}, errors.Wrapf(err, "Query %s was created with an unexpected ID", queryID)
+	}
+	return id, nil
+}
+
+// QueryWithBody queries the collection of resources usage on a given location,
+// providing query parameters in a JSON body, which allows to define complex
+// parameters like nested time ranges or tag filters.
+// body is marshaled to JSON. No query string parameter is sent with this request,
+// the parameters defined in body being the only ones provided to the collector.
+// The ID of a query that will perform the collection is returned
+func (u *usageCollectorService) QueryWithBody(ctx context.Context, orchestratorName, collectorID, location string, body interface{}) (string, error) {
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return "", errors.Wrapf(err, "Cannot encode the body of query on resources usage for %s %s %s",
+			orchestratorName, collectorID, location)
+	}
+
+	return u.submitQuery(ctx, "QueryWithBody", orchestratorName, collectorID, location, nil, bodyBytes, nil)
+}
+
+// submitQuery submits a query on resources usage with the given query string
+// parameters and body, and returns the ID of the query created.
+// Response status and headers are stored in meta if not nil
+func (u *usageCollectorService) submitQuery(ctx context.Context, operation, orchestratorName, collectorID, location string,
+	query url.Values, body []byte, meta *ResponseMeta) (string, error) {
+
+	var queryID string
+	if u.validateQueries || collectorOrigin(ctx) != "" {
+		if err := u.validateQuery(ctx, orchestratorName, collectorID, location); err != nil {
+			return queryID, err
+		}
+	}
+
+	usageURL, err := url.Parse(fmt.Sprintf("%s/infra_usage/%s/%s",
+		orchestratorPath(u.client.prefix(), orchestratorName), url.PathEscape(collectorID), url.PathEscape(location)))
+	if err != nil {
+		return queryID, err
+	}
+
+	if body == nil {
+		query = mergeQueryParams(u.defaultQueryParams, query)
+	}
+	// Encode sorts parameters by key, whatever the iteration order of the maps
+	// they were built from, so the same parameters always provide the same URL
 	usageURL.RawQuery = query.Encode()
 
-	response, err := u.client.do(
+	response, err := u.client.doWithContext(
+		ctx,
+		operation,
 		"POST",
 		usageURL.String(),
-		nil,
+		body,
 		[]Header{
 			{
 				"Content-Type",
@@ -122,28 +478,108 @@ func (u *usageCollectorService) Query(orchestratorName, collectorID, location st
 			orchestratorName, collectorID, location)
 	}
 	defer response.Body.Close()
+	meta.set(response)
 
-	if response.StatusCode != http.StatusCreated {
-		return queryID, getError(response.Body)
+	// The query is either created, or accepted and queued
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusAccepted {
+		return queryID, getError(response)
 	}
 
 	locationHeader := response.Header["Location"]
 	if len(locationHeader) == 0 || locationHeader[0] == "" {
-		return queryID, errors.Wrapf(err, "No resources usage query could be created for %s %s %s",
+		return queryID, errors.Errorf("No resources usage query could be created for %s %s %s",
 			orchestratorName, collectorID, location)
 	}
 
-	queryIDPrefix := fmt.Sprintf("%s/orchestrators/", yorcProviderRESTPrefix)
-	queryID = strings.TrimPrefix(locationHeader[0], queryIDPrefix)
+	queryID = queryIDFromHRef(locationHeader[0], u.client.prefix())
+	u.tracker.add(queryID)
 
 	return queryID, err
 }
 
+// mergeQueryParams returns query parameters merging default parameters and
+// parameters of a call, which override default parameters having the same key
+func mergeQueryParams(defaults, query url.Values) url.Values {
+	if len(defaults) == 0 {
+		return query
+	}
+	merged := url.Values{}
+	for k, v := range defaults {
+		merged[k] = append([]string(nil), v...)
+	}
+	for k, v := range query {
+		merged[k] = append([]string(nil), v...)
+	}
+	return merged
+}
+
+// validateQuery checks that a collector exists on an orchestrator, provided by
+// the origin selected by the context if any, an error being returned if several
+// collectors have this ID and no origin is selected. When queries validation is
+// enabled, the location is checked to exist as well, only if the plugin provides
+// locations
+func (u *usageCollectorService) validateQuery(ctx context.Context, orchestratorName, collectorID, location string) error {
+	if _, err := u.findUsageCollector(ctx, orchestratorName, collectorID, collectorOrigin(ctx)); err != nil {
+		return err
+	}
+	if !u.validateQueries {
+		return nil
+	}
+
+	locations, err := (&locationService{u.client}).GetLocations(orchestratorName)
+	if err != nil {
+		if IsNotFound(err) {
+			// Locations are not provided by this version of the plugin
+			return nil
+		}
+		return err
+	}
+	var locationNames []string
+	for _, l := range locations {
+		if l.Name == location {
+			return nil
+		}
+		locationNames = append(locationNames, l.Name)
+	}
+	return errors.Wrapf(ErrNotFound, "No location %s found on orchestrator %s. Known locations: %v",
+		location, orchestratorName, locationNames)
+}
+
 // DeleteQuery deletes a query of resources usage collection
 func (u *usageCollectorService) DeleteQuery(queryID string) error {
-	response, err := u.client.do(
+	return u.deleteQuery(context.Background(), queryID)
+}
+
+// DeleteQueryByID deletes a query of resources usage collection identified
+// by a QueryID, as returned by SubmitQuery
+func (u *usageCollectorService) DeleteQueryByID(ctx context.Context, queryID QueryID) error {
+	return u.deleteQuery(ctx, queryID.String())
+}
+
+// DeleteQueryIfTerminal deletes a query of resources usage collection only if
+// it reached a final status (done, failed or canceled), so that a collection
+// still running is not deleted. It returns true if the query was deleted
+func (u *usageCollectorService) DeleteQueryIfTerminal(ctx context.Context, queryID string) (bool, error) {
+	status, err := u.GetQueryStatusWithContext(ctx, queryID)
+	if err != nil {
+		return false, err
+	}
+	if !status.IsTerminal() {
+		return false, nil
+	}
+	if err = u.deleteQuery(ctx, queryID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// deleteQuery deletes a query of resources usage collection with a Context that can be canceled
+func (u *usageCollectorService) deleteQuery(ctx context.Context, queryID string) error {
+	response, err := u.client.doWithContext(
+		ctx,
+		"DeleteQuery",
 		"DELETE",
-		fmt.Sprintf("%s/orchestrators/%s", yorcProviderRESTPrefix, queryID),
+		queryPath(u.client.prefix(), queryID),
 		nil,
 		[]Header{
 			{
@@ -158,10 +594,95 @@ func (u *usageCollectorService) DeleteQuery(queryID string) error {
 	}
 	defer response.Body.Close()
 
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNoContent {
+		return getNotFoundError(response, ErrQueryNotFound)
+	}
+
+	u.tracker.remove(queryID)
+	return nil
+}
+
+// DeleteQueriesOlderThan deletes queries of resources usage collection performed
+// on a given orchestrator for a given collector, which are older than age, and
+// returns the number of queries deleted.
+//
+// Limitation: the REST API doesn't expose the creation time of queries, neither
+// in the list of queries nor in their href, so the age of a query can't be
+// checked and age is ignored. As done by DeleteTerminalQueries, queries having
+// reached a final status (done, failed or canceled) are deleted whatever their
+// age, while queries still running are kept
+func (u *usageCollectorService) DeleteQueriesOlderThan(ctx context.Context, orchestratorName, collectorID string, age time.Duration) (int, error) {
+	return u.DeleteTerminalQueries(ctx, orchestratorName, collectorID)
+}
+
+// DeleteTerminalQueries deletes queries of resources usage collection performed
+// on a given orchestrator for a given collector, which reached a final status
+// (done, failed or canceled), and returns the number of queries deleted.
+// Queries still running are kept.
+// A failure to delete a query doesn't prevent other queries from being deleted,
+// a BatchError providing the error per query ID being returned
+func (u *usageCollectorService) DeleteTerminalQueries(ctx context.Context, orchestratorName, collectorID string) (int, error) {
+	queries, err := u.getQueries(ctx, orchestratorName, collectorID)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	batchErr := &BatchError{Errors: make(map[string]error)}
+	for _, q := range queries {
+		if ctx.Err() != nil {
+			batchErr.Errors[q.ID] = ctx.Err()
+			continue
+		}
+		ok, err := u.DeleteQueryIfTerminal(ctx, q.ID)
+		if err != nil {
+			batchErr.Errors[q.ID] = err
+			continue
+		}
+		if ok {
+			deleted++
+		}
+	}
+
+	if len(batchErr.Errors) > 0 {
+		return deleted, batchErr
+	}
+	return deleted, nil
+}
+
+// CancelQuery cancels a query of resources usage collection.
+// Contrary to DeleteQuery, the query is kept and its status becomes QueryStatusCanceled
+func (u *usageCollectorService) CancelQuery(queryID string) error {
+	return u.CancelQueryWithContext(context.Background(), queryID)
+}
+
+// CancelQueryWithContext cancels a query of resources usage collection
+// with a Context that can be canceled
+func (u *usageCollectorService) CancelQueryWithContext(ctx context.Context, queryID string) error {
+	response, err := u.client.doWithContext(
+		ctx,
+		"CancelQuery",
+		"POST",
+		queryPath(u.client.prefix(), queryID)+"/cancel",
+		nil,
+		[]Header{
+			{
+				"Content-Type",
+				"application/json",
+			},
+		},
+	)
+
+	if err != nil {
+		return errors.Wrapf(err, "Unable to send request to cancel query %s", queryID)
+	}
+	defer response.Body.Close()
+
 	if response.StatusCode != http.StatusOK {
-		return getError(response.Body)
+		return getNotFoundError(response, ErrQueryNotFound)
 	}
 
+	u.tracker.remove(queryID)
 	return nil
 }
 
@@ -169,9 +690,33 @@ func (u *usageCollectorService) DeleteQuery(queryID string) error {
 // on a given orchestrator for a given collector
 func (u *usageCollectorService) GetQueryIDs(orchestratorName, collectorID string) ([]string, error) {
 
-	response, err := u.client.do(
+	queries, err := u.GetQueries(orchestratorName, collectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, q := range queries {
+		result = append(result, q.ID)
+	}
+	return result, err
+}
+
+// GetQueries returns resources usage queries performed
+// on a given orchestrator for a given collector
+func (u *usageCollectorService) GetQueries(orchestratorName, collectorID string) ([]QueryInfo, error) {
+	return u.getQueries(context.Background(), orchestratorName, collectorID)
+}
+
+// getQueries returns resources usage queries performed on a given orchestrator
+// for a given collector, with a Context that can be canceled
+func (u *usageCollectorService) getQueries(ctx context.Context, orchestratorName, collectorID string) ([]QueryInfo, error) {
+
+	response, err := u.client.doWithContext(
+		ctx,
+		"GetQueries",
 		"GET",
-		fmt.Sprintf("%s/orchestrators/%s/infra_usage", yorcProviderRESTPrefix, orchestratorName),
+		orchestratorPath(u.client.prefix(), orchestratorName)+"/infra_usage",
 		nil,
 		[]Header{
 			{
@@ -182,18 +727,22 @@ func (u *usageCollectorService) GetQueryIDs(orchestratorName, collectorID string
 	)
 
 	if err != nil {
-		return nil, errors.Wrapf(err, "Unable to send request to get query IDs on %s", orchestratorName)
+		return nil, errors.Wrapf(err, "Unable to send request to get queries on %s", orchestratorName)
 	}
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return nil, getError(response.Body)
+		return nil, getNotFoundError(response, ErrOrchestratorNotFound)
+	}
+
+	if err = checkJSONResponse(response); err != nil {
+		return nil, err
 	}
 
 	responseBody, err := ioutil.ReadAll(response.Body)
 
 	if err != nil {
-		return nil, errors.Wrapf(err, "Unable to read response to get query IDs on %s", orchestratorName)
+		return nil, errors.Wrapf(err, "Unable to read response to get queries on %s", orchestratorName)
 	}
 
 	var res struct {
@@ -202,36 +751,166 @@ func (u *usageCollectorService) GetQueryIDs(orchestratorName, collectorID string
 				Rel  string `json:"rel,omitempty"`
 				HRef string `json:"href,omitempty"`
 				Type string `json:"type,omitempty"`
+				// Status is not provided by all versions of the plugin
+				Status QueryStatus `json:"status,omitempty"`
 			} `json:"tasks,omitempty"`
 		} `json:"data"`
 	}
-	if err = json.Unmarshal([]byte(responseBody), &res); err != nil {
-		return nil, errors.Wrapf(err, "Cannot convert the body of response to get query IDs on %s", orchestratorName)
+	if err = u.client.unmarshal(responseBody, &res); err != nil {
+		return nil, errors.Wrapf(err, "Cannot convert the body of response to get queries on %s", orchestratorName)
 	}
 
 	// Getting query IDs from href
-	var result []string
-	queryIDPrefix := fmt.Sprintf("%s/orchestrators/", yorcProviderRESTPrefix)
+	var result []QueryInfo
 	for _, t := range res.Data.Tasks {
-		s := strings.TrimPrefix(t.HRef, queryIDPrefix)
+		s := queryIDFromHRef(t.HRef, u.client.prefix())
 		if collectorID != "" {
-			// String format <orchestrator>/infra_usage/<collector>/tasks/<id>
-			values := strings.Split(s, "/")
-			if len(values) > 3 || values[2] != collectorID {
+			id, err := ParseQueryID(s)
+			if err != nil || id.Collector != collectorID {
 				// This query is for another collector
-				break
+				continue
 			}
 		}
-		result = append(result, s)
+		result = append(result, QueryInfo{
+			ID:     s,
+			Type:   t.Type,
+			Rel:    t.Rel,
+			HRef:   t.HRef,
+			Status: t.Status,
+		})
+	}
+	return result, err
+}
+
+// GetQueriesWithStatus returns resources usage queries performed on a given
+// orchestrator for a given collector, along with their status.
+// When the status of queries is not provided by the list of queries, statuses
+// are retrieved concurrently, the number of concurrent requests being limited
+// by the client maximum concurrency. Queries for which the status couldn't be
+// retrieved are returned without status, along with a BatchError providing
+// the error per query ID
+func (u *usageCollectorService) GetQueriesWithStatus(ctx context.Context, orchestratorName, collectorID string) ([]QueryInfo, error) {
+	queries, err := u.getQueries(ctx, orchestratorName, collectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, q := range queries {
+		if q.Status == "" {
+			missing = append(missing, q.ID)
+		}
+	}
+	if len(missing) == 0 {
+		return queries, nil
+	}
+
+	statuses := make(map[string]interface{})
+	err = u.runConcurrently(ctx, missing, func(ctx context.Context, queryID string) (interface{}, error) {
+		return u.GetQueryStatusWithContext(ctx, queryID)
+	}, statuses)
+
+	for i := range queries {
+		if status, ok := statuses[queries[i].ID]; ok {
+			queries[i].Status = status.(QueryStatus)
+		}
+	}
+	return queries, err
+}
+
+// GetQueriesSorted returns resources usage queries performed on a given
+// orchestrator for a given collector, newest first.
+// The REST API doesn't provide the creation time of queries, so queries are
+// sorted by task ID, in descending order, task IDs being compared as numbers
+// when they are numbers
+func (u *usageCollectorService) GetQueriesSorted(orchestratorName, collectorID string) ([]QueryInfo, error) {
+	return u.getQueriesSorted(context.Background(), orchestratorName, collectorID)
+}
+
+// getQueriesSorted returns resources usage queries performed on a given
+// orchestrator for a given collector, newest first, with a Context that can be canceled
+func (u *usageCollectorService) getQueriesSorted(ctx context.Context, orchestratorName, collectorID string) ([]QueryInfo, error) {
+	queries, err := u.getQueries(ctx, orchestratorName, collectorID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(queries, func(i, j int) bool {
+		return taskIDLess(taskID(queries[j].ID), taskID(queries[i].ID))
+	})
+	return queries, nil
+}
+
+// GetQueriesPage returns a page of at most limit resources usage queries
+// performed on a given orchestrator for a given collector, starting at offset,
+// along with the total number of queries. Queries are ordered newest first,
+// as returned by GetQueriesSorted.
+// The REST API doesn't support paging, so all queries are retrieved and
+// paged by the client
+func (u *usageCollectorService) GetQueriesPage(ctx context.Context, orchestratorName, collectorID string, offset, limit int) ([]QueryInfo, int, error) {
+	if offset < 0 || limit < 0 {
+		return nil, 0, errors.Errorf("Offset and limit must not be negative, got offset %d and limit %d", offset, limit)
+	}
+	queries, err := u.getQueriesSorted(ctx, orchestratorName, collectorID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total := len(queries)
+	if offset >= total {
+		return nil, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return queries[offset:end], total, nil
+}
+
+// GetQueriesByStatus returns resources usage queries performed on a given
+// orchestrator for a given collector, which have a given status.
+// Statuses are retrieved as done by GetQueriesWithStatus, queries for which
+// the status couldn't be retrieved being returned in a BatchError
+func (u *usageCollectorService) GetQueriesByStatus(ctx context.Context, orchestratorName, collectorID string, status QueryStatus) ([]QueryInfo, error) {
+	queries, err := u.GetQueriesWithStatus(ctx, orchestratorName, collectorID)
+	if queries == nil {
+		return nil, err
+	}
+
+	var result []QueryInfo
+	for _, q := range queries {
+		if q.Status == status {
+			result = append(result, q)
+		}
 	}
 	return result, err
 }
 
 // GetCollectedUsage gets results of a resources usage collection query
 func (u *usageCollectorService) GetCollectedUsage(queryID string) (*UsageCollection, error) {
-	response, err := u.client.do(
+	return u.GetCollectedUsageWithContext(context.Background(), queryID)
+}
+
+// GetCollectedUsageWithContext gets results of a resources usage collection query
+// with a Context that can be canceled
+func (u *usageCollectorService) GetCollectedUsageWithContext(ctx context.Context, queryID string) (*UsageCollection, error) {
+	return u.GetCollectedUsageWithMeta(ctx, queryID, nil)
+}
+
+// GetCollectedUsageByID gets results of a resources usage collection query
+// identified by a QueryID, as returned by SubmitQuery
+func (u *usageCollectorService) GetCollectedUsageByID(ctx context.Context, queryID QueryID) (*UsageCollection, error) {
+	return u.GetCollectedUsageWithMeta(ctx, queryID.String(), nil)
+}
+
+// GetCollectedUsageWithMeta gets results of a resources usage collection query
+// with a Context that can be canceled, storing the status and headers of the
+// response in meta
+func (u *usageCollectorService) GetCollectedUsageWithMeta(ctx context.Context, queryID string, meta *ResponseMeta) (*UsageCollection, error) {
+	response, err := u.client.doWithContext(
+		ctx,
+		"GetCollectedUsage",
 		"GET",
-		fmt.Sprintf("%s/orchestrators/%s", yorcProviderRESTPrefix, queryID),
+		queryPath(u.client.prefix(), queryID),
 		nil,
 		[]Header{
 			{
@@ -245,9 +924,14 @@ func (u *usageCollectorService) GetCollectedUsage(queryID string) (*UsageCollect
 		return nil, errors.Wrapf(err, "Unable to send request to get usage collected by query %s", queryID)
 	}
 	defer response.Body.Close()
+	meta.set(response)
 
 	if response.StatusCode != http.StatusOK {
-		return nil, getError(response.Body)
+		return nil, getNotFoundError(response, ErrQueryNotFound)
+	}
+
+	if err = checkJSONResponse(response); err != nil {
+		return nil, err
 	}
 
 	responseBody, err := ioutil.ReadAll(response.Body)
@@ -256,22 +940,248 @@ func (u *usageCollectorService) GetCollectedUsage(queryID string) (*UsageCollect
 		return nil, errors.Wrapf(err, "Unable to read response to get usage collected by query %s", queryID)
 	}
 
+	var res struct {
+		Data usageCollectionData `json:"data"`
+	}
+	if err = u.client.unmarshal(responseBody, &res); err != nil {
+		return nil, errors.Wrapf(err, "Cannot convert the body of response to get usage collected by query %s: %s", queryID, string(responseBody))
+	}
+
+	result := res.Data.collection()
+	if result.QueryStatus().IsTerminal() {
+		u.tracker.remove(queryID)
+	}
+	return result, err
+}
+
+// StreamCollectedUsage gets results of a resources usage collection query
+// with a Context that can be canceled, calling handler on each top-level entry
+// of the results, as they are decoded from the response. Contrary to
+// GetCollectedUsage, the whole results are not held in memory, which allows to
+// process large results incrementally.
+// An error returned by handler stops the processing and is returned
+func (u *usageCollectorService) StreamCollectedUsage(ctx context.Context, queryID string, handler func(key string, value json.RawMessage) error) error {
+	response, err := u.client.doWithContext(
+		ctx,
+		"StreamCollectedUsage",
+		"GET",
+		queryPath(u.client.prefix(), queryID),
+		nil,
+		[]Header{
+			{
+				"Content-Type",
+				"application/json",
+			},
+		},
+	)
+
+	if err != nil {
+		return errors.Wrapf(err, "Unable to send request to get usage collected by query %s", queryID)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return getNotFoundError(response, ErrQueryNotFound)
+	}
+
+	if err = checkJSONResponse(response); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(response.Body)
+	err = decodeObject(decoder, func(key string) error {
+		if key != "data" {
+			return skipValue(decoder)
+		}
+		return decodeObject(decoder, func(key string) error {
+			if key != "result_set" {
+				return skipValue(decoder)
+			}
+			return decodeObject(decoder, func(key string) error {
+				var value json.RawMessage
+				if err := decoder.Decode(&value); err != nil {
+					return err
+				}
+				return handler(key, value)
+			})
+		})
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Cannot process the body of response to get usage collected by query %s", queryID)
+	}
+	return nil
+}
+
+// GetQueryStatus gets the status of a resources usage collection query,
+// without decoding the results collected
+func (u *usageCollectorService) GetQueryStatus(queryID string) (QueryStatus, error) {
+	return u.GetQueryStatusWithContext(context.Background(), queryID)
+}
+
+// GetQueryStatusWithContext gets the status of a resources usage collection query
+// with a Context that can be canceled, without decoding the results collected
+func (u *usageCollectorService) GetQueryStatusWithContext(ctx context.Context, queryID string) (QueryStatus, error) {
+	status, _, err := u.getQueryStatus(ctx, queryID)
+	return status, err
+}
+
+// getQueryStatus gets the status of a resources usage collection query, and
+// its progress percentage, -1 if unknown
+func (u *usageCollectorService) getQueryStatus(ctx context.Context, queryID string) (QueryStatus, int, error) {
+	response, err := u.client.doWithContext(
+		ctx,
+		"GetQueryStatus",
+		"GET",
+		queryPath(u.client.prefix(), queryID),
+		nil,
+		[]Header{
+			{
+				"Content-Type",
+				"application/json",
+			},
+		},
+	)
+
+	if err != nil {
+		return "", -1, errors.Wrapf(err, "Unable to send request to get status of query %s", queryID)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", -1, getNotFoundError(response, ErrQueryNotFound)
+	}
+
+	if err = checkJSONResponse(response); err != nil {
+		return "", -1, err
+	}
+
+	// Decoding only the status and progress, results being skipped by the decoder
 	var res struct {
 		Data struct {
-			ID       string                 `json:"id,omitempty"`
-			TargetID string                 `json:"target_id,omitempty"`
-			Type     string                 `json:"type,omitempty"`
-			Status   string                 `json:"status,omitempty"`
-			Results  map[string]interface{} `json:"result_set,omitempty"`
+			Status   QueryStatus `json:"status"`
+			Progress *float64    `json:"progress"`
 		} `json:"data"`
 	}
-	if err = json.Unmarshal(responseBody, &res); err != nil {
-		return nil, errors.Wrapf(err, "Cannot convert the body of response to get collectors on %s: %s", queryID, string(responseBody))
+	if err = json.NewDecoder(response.Body).Decode(&res); err != nil {
+		return "", -1, errors.Wrapf(err, "Cannot convert the body of response to get status of query %s", queryID)
+	}
+
+	if res.Data.Status.IsTerminal() {
+		u.tracker.remove(queryID)
+	}
+	return res.Data.Status, progressPercent(res.Data.Progress), nil
+}
+
+// WaitForQueryCompletion polls the status of a resources usage collection query
+// every pollInterval until it reaches a final status, and returns its results.
+// A pollInterval lower or equal to 0 means the default interval of one second.
+// An error is returned if the query failed or was canceled, along with the
+// collection
+func (u *usageCollectorService) WaitForQueryCompletion(ctx context.Context, queryID string, pollInterval time.Duration) (*UsageCollection, error) {
+	return u.WaitForQueryCompletionFunc(ctx, queryID, pollInterval, nil)
+}
+
+// WaitForQueryCompletionFunc waits for the end of a resources usage collection
+// query like WaitForQueryCompletion, calling onStatus, if not nil, with the
+// status and progress percentage of the query each time they differ from those
+// previously polled, including the first time. Progress is -1 when unknown
+func (u *usageCollectorService) WaitForQueryCompletionFunc(ctx context.Context, queryID string, pollInterval time.Duration, onStatus func(status QueryStatus, progress int)) (*UsageCollection, error) {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var previous QueryStatus
+	previousProgress := -1
+	for {
+		status, progress, err := u.getQueryStatus(ctx, queryID)
+		if err != nil {
+			return nil, err
+		}
+		if onStatus != nil && (status != previous || progress != previousProgress) {
+			onStatus(status, progress)
+		}
+		previous = status
+		previousProgress = progress
+
+		if status.IsTerminal() {
+			// Fetching results once the query is over
+			collection, err := u.GetCollectedUsageWithContext(ctx, queryID)
+			if err != nil {
+				return nil, err
+			}
+			if collection.Status != QueryStatusDone {
+				if collection.FailureReason != "" {
+					return collection, errors.Errorf("Query %s ended with status %s: %s", queryID, collection.Status, collection.FailureReason)
+				}
+				return collection, errors.Errorf("Query %s ended with status %s", queryID, collection.Status)
+			}
+			return collection, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "Stopped waiting for the end of query %s", queryID)
+		case <-ticker.C:
+		}
+	}
+}
+
+// QueryAll queries the collection of resources usage on several locations concurrently,
+// waits for the end of these collections and returns collected usage per location.
+// The number of locations processed concurrently is limited by the client
+// maximum concurrency. Queries are kept once done, unless WithQueryCleanup is used.
+// A failure on a location doesn't prevent other locations from being processed:
+// usage collected on other locations is returned along with a BatchError
+// providing the error per location. The collection of a query which failed or
+// was canceled is returned as well, providing its status and failure reason
+func (u *usageCollectorService) QueryAll(ctx context.Context, orchestratorName, collectorID string, locations []string, queryParameters map[string]string) (map[string]*UsageCollection, error) {
+
+	var lock sync.Mutex
+	failed := make(map[string]*UsageCollection)
+	collections := make(map[string]interface{})
+	err := u.runConcurrently(ctx, locations, func(ctx context.Context, location string) (interface{}, error) {
+		collection, err := u.queryAndWait(ctx, orchestratorName, collectorID, location, queryParameters)
+		if err != nil && collection != nil {
+			lock.Lock()
+			failed[location] = collection
+			lock.Unlock()
+		}
+		return collection, err
+	}, collections)
+
+	results := make(map[string]*UsageCollection)
+	for location, collection := range collections {
+		results[location] = collection.(*UsageCollection)
+	}
+	for location, collection := range failed {
+		results[location] = collection
+	}
+	return results, err
+}
+
+// runConcurrently calls fn on each key, the number of concurrent calls being
+// limited by the client maximum concurrency. Values returned are stored in
+// results. A BatchError providing the error per key is returned if any call failed
+func (u *usageCollectorService) runConcurrently(ctx context.Context, keys []string,
+	fn func(ctx context.Context, key string) (interface{}, error), results map[string]interface{}) error {
+	return runPool(ctx, u.client.concurrency(), keys, fn, results)
+}
+
+// queryAndWait queries the collection of resources usage on a location and
+// waits for the end of the collection. The query is deleted if it is done
+// and query cleanup is enabled
+func (u *usageCollectorService) queryAndWait(ctx context.Context, orchestratorName, collectorID, location string, queryParameters map[string]string) (*UsageCollection, error) {
+	queryID, err := u.QueryWithContext(ctx, orchestratorName, collectorID, location, queryParameters)
+	if err != nil {
+		return nil, err
 	}
 
-	result := UsageCollection{
-		Status:  res.Data.Status,
-		Results: res.Data.Results,
+	collection, err := u.WaitForQueryCompletion(ctx, queryID, defaultPollInterval)
+	if u.cleanupQueries && err == nil && collection.Status == QueryStatusDone {
+		// Best effort cleanup, failed queries being kept to allow to inspect them
+		u.DeleteQuery(queryID)
 	}
-	return &result, err
+	return collection, err
 }
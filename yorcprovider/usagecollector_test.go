@@ -0,0 +1,475 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// collectedUsageResponse is a response of the REST API providing the usage
+// collected by a query on a Slurm location
+const collectedUsageResponse = `{
+  "data": {
+    "id": "1234",
+    "target_id": "slurm-hpc",
+    "type": "slurm",
+    "status": "DONE",
+    "result_set": {
+      "nodes_total": 12,
+      "nodes_allocated": 5,
+      "cpus_total": 288,
+      "partitions": [
+        {"name": "debug", "nodes_total": 2}
+      ]
+    }
+  }
+}`
+
+// failedQueryResponse is a response of the REST API providing a query which
+// failed to collect the usage of a Slurm location
+const failedQueryResponse = `{
+  "data": {
+    "id": "1235",
+    "target_id": "slurm-hpc",
+    "type": "slurm",
+    "status": "%s",
+    "error": "Failed to run sinfo: slurm_load_partitions: Unable to contact slurm controller"
+  }
+}`
+
+func TestGetQueryIDsFiltersCollectors(t *testing.T) {
+	tasksPath := DefaultRESTPrefix + "/orchestrators/yorc/infra_usage"
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tasksPath {
+			t.Errorf("Unexpected request path %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		hrefPrefix := DefaultRESTPrefix + "/orchestrators/yorc/infra_usage/"
+		writeTestData(t, w, http.StatusOK, map[string]interface{}{
+			"tasks": []map[string]string{
+				{"rel": "task", "href": hrefPrefix + "slurm/tasks/1"},
+				{"rel": "task", "href": hrefPrefix + "openstack/tasks/2"},
+				{"rel": "task", "href": hrefPrefix + "slurm/tasks/3"},
+				{"rel": "task", "href": hrefPrefix + "hostspool/tasks/4"},
+				{"rel": "task", "href": hrefPrefix + "slurm/tasks/5"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		collectorID string
+		want        []string
+	}{
+		{"slurm", []string{"yorc/infra_usage/slurm/tasks/1", "yorc/infra_usage/slurm/tasks/3", "yorc/infra_usage/slurm/tasks/5"}},
+		{"openstack", []string{"yorc/infra_usage/openstack/tasks/2"}},
+		{"google", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.collectorID, func(t *testing.T) {
+			got, err := client.UsageCollectorService().GetQueryIDs("yorc", tt.collectorID)
+			if err != nil {
+				t.Fatalf("GetQueryIDs() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetQueryIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCollectedUsageDecodesResponse(t *testing.T) {
+	queryID := "yorc/infra_usage/slurm/tasks/1234"
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != DefaultRESTPrefix+"/orchestrators/"+queryID {
+			t.Errorf("Unexpected request path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(collectedUsageResponse))
+	}))
+	defer server.Close()
+
+	collection, err := client.UsageCollectorService().GetCollectedUsage(queryID)
+	if err != nil {
+		t.Fatalf("GetCollectedUsage() error = %v", err)
+	}
+	if collection.ID != "1234" || collection.TargetID != "slurm-hpc" || collection.Type != "slurm" ||
+		collection.Status != QueryStatusDone {
+		t.Errorf("GetCollectedUsage() = %+v, want query 1234 DONE on slurm-hpc", collection)
+	}
+	if got := collection.Results["nodes_total"]; got != float64(12) {
+		t.Errorf("Results[nodes_total] = %v, want 12", got)
+	}
+
+	var usage SlurmUsage
+	if err = collection.UnmarshalResults(&usage); err != nil {
+		t.Fatalf("UnmarshalResults() error = %v", err)
+	}
+	want := SlurmUsage{
+		NodesTotal:     12,
+		NodesAllocated: 5,
+		CPUsTotal:      288,
+		Partitions:     []SlurmPartitionUsage{{Name: "debug", NodesTotal: 2}},
+	}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("UnmarshalResults() = %+v, want %+v", usage, want)
+	}
+}
+
+func TestGetCollectedUsageDecodesProgress(t *testing.T) {
+	tests := []struct {
+		progress string
+		want     int
+	}{
+		{``, -1},
+		{`, "progress": 42.5`, 42},
+		{`, "progress": 150`, 100},
+		{`, "progress": -5`, 0},
+	}
+	for _, tt := range tests {
+		client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"data": {"id": "1", "status": "RUNNING"` + tt.progress + `}}`))
+		}))
+
+		collection, err := client.UsageCollectorService().GetCollectedUsage("yorc/infra_usage/slurm/tasks/1")
+		server.Close()
+		if err != nil {
+			t.Fatalf("GetCollectedUsage() with progress %q error = %v", tt.progress, err)
+		}
+		if collection.Progress != tt.want {
+			t.Errorf("GetCollectedUsage() with progress %q Progress = %d, want %d", tt.progress, collection.Progress, tt.want)
+		}
+	}
+}
+
+func TestGetCollectedUsageDecodesFailureReason(t *testing.T) {
+	const reason = "Failed to run sinfo: slurm_load_partitions: Unable to contact slurm controller"
+	tests := []struct {
+		status     QueryStatus
+		wantReason string
+	}{
+		{QueryStatusFailed, reason},
+		{QueryStatusDone, ""},
+		{QueryStatusCanceled, ""},
+	}
+	for _, tt := range tests {
+		client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, failedQueryResponse, tt.status)
+		}))
+
+		collection, err := client.UsageCollectorService().GetCollectedUsage("yorc/infra_usage/slurm/tasks/1235")
+		server.Close()
+		if err != nil {
+			t.Fatalf("GetCollectedUsage() of a %s query error = %v", tt.status, err)
+		}
+		if collection.QueryStatus() != tt.status || collection.FailureReason != tt.wantReason {
+			t.Errorf("GetCollectedUsage() of a %s query = %+v, want status %s and failure reason %q",
+				tt.status, collection, tt.status, tt.wantReason)
+		}
+	}
+}
+
+func TestWaitForQueryCompletionFailedQuery(t *testing.T) {
+	const reason = "slurm controller unreachable"
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"id": "1", "type": "slurm", "status": "FAILED", "error": "` + reason + `"}}`))
+	}))
+	defer server.Close()
+
+	collection, err := client.UsageCollectorService().WaitForQueryCompletion(
+		context.Background(), "yorc/infra_usage/slurm/tasks/1", time.Millisecond)
+	if err == nil {
+		t.Fatal("WaitForQueryCompletion() on a failed query succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), reason) {
+		t.Errorf("WaitForQueryCompletion() error = %v, want it to contain %q", err, reason)
+	}
+	if collection == nil || collection.Status != QueryStatusFailed || collection.FailureReason != reason {
+		t.Errorf("WaitForQueryCompletion() = %+v, want a FAILED query with reason %q", collection, reason)
+	}
+}
+
+func TestUsageCollectionQueryStatus(t *testing.T) {
+	// Status remains a string, comparable to query status constants
+	collection := UsageCollection{Status: QueryStatusFailed}
+	if collection.Status != QueryStatusFailed {
+		t.Errorf("Status = %s, want %s", collection.Status, QueryStatusFailed)
+	}
+	if status := collection.QueryStatus(); status != QueryStatusFailed || !status.IsTerminal() {
+		t.Errorf("QueryStatus() = %s, want terminal status %s", status, QueryStatusFailed)
+	}
+}
+
+func TestDeleteQueryStatusCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantError  bool
+	}{
+		{"OK", http.StatusOK, false},
+		{"NoContent", http.StatusNoContent, false},
+		{"NotFound", http.StatusNotFound, true},
+		{"InternalServerError", http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete {
+					t.Errorf("Unexpected method %s", r.Method)
+				}
+				// Empty body, as returned by some gateways
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			err := client.UsageCollectorService().DeleteQuery("yorc/infra_usage/slurm/tasks/1")
+			if (err != nil) != tt.wantError {
+				t.Fatalf("DeleteQuery() error = %v, want error %t", err, tt.wantError)
+			}
+			if err != nil && !strings.Contains(err.Error(), strconv.Itoa(tt.statusCode)) {
+				t.Errorf("DeleteQuery() error %q doesn't provide the status code", err.Error())
+			}
+		})
+	}
+}
+
+func TestDeleteQueryIfTerminal(t *testing.T) {
+	for _, status := range []QueryStatus{QueryStatusRunning, QueryStatusDone, QueryStatusFailed} {
+		deletes := 0
+		client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodDelete {
+				deletes++
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			writeTestData(t, w, http.StatusOK, map[string]interface{}{"id": "1", "status": status})
+		}))
+
+		deleted, err := client.UsageCollectorService().DeleteQueryIfTerminal(context.Background(), "yorc/infra_usage/slurm/tasks/1")
+		server.Close()
+		if err != nil {
+			t.Fatalf("DeleteQueryIfTerminal() on a %s query error = %v", status, err)
+		}
+		wantDeleted := status.IsTerminal()
+		if deleted != wantDeleted {
+			t.Errorf("DeleteQueryIfTerminal() on a %s query = %t, want %t", status, deleted, wantDeleted)
+		}
+		wantDeletes := 0
+		if wantDeleted {
+			wantDeletes = 1
+		}
+		if deletes != wantDeletes {
+			t.Errorf("DeleteQueryIfTerminal() on a %s query sent %d delete requests, want %d", status, deletes, wantDeletes)
+		}
+	}
+}
+
+func TestQueryAcceptedWithLocation(t *testing.T) {
+	for _, statusCode := range []int{http.StatusCreated, http.StatusAccepted} {
+		t.Run(http.StatusText(statusCode), func(t *testing.T) {
+			client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("Unexpected method %s", r.Method)
+				}
+				w.Header().Set("Location", DefaultRESTPrefix+"/orchestrators/yorc/infra_usage/slurm/tasks/42")
+				w.WriteHeader(statusCode)
+			}))
+			defer server.Close()
+
+			queryID, err := client.UsageCollectorService().Query("yorc", "slurm", "hpc", nil)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if want := "yorc/infra_usage/slurm/tasks/42"; queryID.String() != want {
+				t.Errorf("Query() = %q, want %q", queryID, want)
+			}
+		})
+	}
+}
+
+func TestQueryUnexpectedIDIsProvided(t *testing.T) {
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", DefaultRESTPrefix+"/orchestrators/yorc/tasks/42")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	_, err := client.UsageCollectorService().Query("yorc", "slurm", "hpc", nil)
+	if err == nil {
+		t.Fatal("Query() with an unexpected query ID succeeded, want an error")
+	}
+	// The query was created, its ID must be available to delete it
+	if !strings.Contains(err.Error(), "yorc/tasks/42") {
+		t.Errorf("Query() error = %v, want it to provide the query ID", err)
+	}
+}
+
+func TestQueryWithoutLocation(t *testing.T) {
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	if _, err := client.UsageCollectorService().Query("yorc", "slurm", "hpc", nil); err == nil {
+		t.Error("Query() without Location header succeeded, want an error")
+	}
+}
+
+func TestQueryWithValuesMultiValuedParameters(t *testing.T) {
+	params := url.Values{
+		"resource": {"cpu", "mem"},
+		"filter":   {"partition=debug&state=idle", "name:n 1/2+é"},
+	}
+	var got url.Values
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query()
+		w.Header().Set("Location", DefaultRESTPrefix+"/orchestrators/yorc/infra_usage/slurm/tasks/1")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	if _, err := client.UsageCollectorService().QueryWithValues(context.Background(), "yorc", "slurm", "hpc", params); err != nil {
+		t.Fatalf("QueryWithValues() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, params) {
+		t.Errorf("QueryWithValues() sent parameters %v, want %v", got, params)
+	}
+}
+
+func TestQueryStringIsDeterministic(t *testing.T) {
+	params := map[string]string{
+		"start":     "2021-01-01T00:00:00Z",
+		"end":       "2021-02-01T00:00:00Z",
+		"partition": "debug",
+		"user":      "jdoe",
+		"account":   "hpc project",
+		"format":    "json",
+	}
+	var queries []string
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		w.Header().Set("Location", DefaultRESTPrefix+"/orchestrators/yorc/infra_usage/slurm/tasks/1")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	for i := 0; i < 20; i++ {
+		if _, err := client.UsageCollectorService().Query("yorc", "slurm", "hpc", params); err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+	}
+	want := "account=hpc+project&end=2021-02-01T00%3A00%3A00Z&format=json&partition=debug&start=2021-01-01T00%3A00%3A00Z&user=jdoe"
+	for _, query := range queries {
+		if query != want {
+			t.Fatalf("Query() sent query string %s, want %s", query, want)
+		}
+	}
+}
+
+func TestRequestPathsAreEscaped(t *testing.T) {
+	orchestratorPath := DefaultRESTPrefix + "/orchestrators/my%20orchestrator"
+	var gotPath string
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		switch r.Method {
+		case http.MethodPost:
+			w.Header().Set("Location", orchestratorPath+"/infra_usage/my%20collector/tasks/1")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			writeTestData(t, w, http.StatusOK, map[string]interface{}{})
+		}
+	}))
+	defer server.Close()
+	service := client.UsageCollectorService()
+
+	queryID, err := service.Query("my orchestrator", "my collector", "my location/zone-1", nil)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if want := orchestratorPath + "/infra_usage/my%20collector/my%20location%2Fzone-1"; gotPath != want {
+		t.Errorf("Query() request path = %s, want %s", gotPath, want)
+	}
+	if want := "my orchestrator/infra_usage/my collector/tasks/1"; queryID.String() != want {
+		t.Errorf("Query() = %q, want %q", queryID, want)
+	}
+
+	if _, err = service.GetCollectedUsageByID(context.Background(), queryID); err != nil {
+		t.Fatalf("GetCollectedUsage() error = %v", err)
+	}
+	if want := orchestratorPath + "/infra_usage/my%20collector/tasks/1"; gotPath != want {
+		t.Errorf("GetCollectedUsage() request path = %s, want %s", gotPath, want)
+	}
+
+	if _, err = service.GetUsageCollectors("my orchestrator"); err != nil {
+		t.Fatalf("GetUsageCollectors() error = %v", err)
+	}
+	if want := orchestratorPath + "/registry/infra_usage_collectors"; gotPath != want {
+		t.Errorf("GetUsageCollectors() request path = %s, want %s", gotPath, want)
+	}
+
+	if _, err = service.GetQueryIDs("my orchestrator", "my collector"); err != nil {
+		t.Fatalf("GetQueryIDs() error = %v", err)
+	}
+	if want := orchestratorPath + "/infra_usage"; gotPath != want {
+		t.Errorf("GetQueryIDs() request path = %s, want %s", gotPath, want)
+	}
+}
+
+func TestQueryIDVariants(t *testing.T) {
+	queryID := QueryID{Orchestrator: "yorc", Collector: "slurm", TaskID: "1"}
+	taskPath := DefaultRESTPrefix + "/orchestrators/yorc/infra_usage/slurm/tasks/1"
+	var requests []string
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusOK)
+		default:
+			writeTestData(t, w, http.StatusOK, map[string]interface{}{
+				"id":     "1",
+				"status": "DONE",
+			})
+		}
+	}))
+	defer server.Close()
+	service := client.UsageCollectorService()
+
+	collection, err := service.GetCollectedUsageByID(context.Background(), queryID)
+	if err != nil {
+		t.Fatalf("GetCollectedUsageByID() error = %v", err)
+	}
+	if collection.ID != "1" {
+		t.Errorf("GetCollectedUsageByID() ID = %q, want %q", collection.ID, "1")
+	}
+	if err = service.DeleteQueryByID(context.Background(), queryID); err != nil {
+		t.Fatalf("DeleteQueryByID() error = %v", err)
+	}
+	want := []string{"GET " + taskPath, "DELETE " + taskPath}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+}
@@ -15,37 +15,255 @@
 package yorcprovider
 
 import (
+	"bytes"
 	"encoding/json"
-	"io"
+	"fmt"
 	"io/ioutil"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
-func getError(body io.ReadCloser) error {
+// maxErrorBodySnippet is the maximum length of a raw response body
+// included in an error message
+const maxErrorBodySnippet = 512
 
-	r, _ := ioutil.ReadAll(body)
-	body.Close()
+// getError returns an APIError built from the status code and body of a response
+func getError(response *http.Response) error {
 
-	var res struct {
-		Error Error `json:"error"`
+	statusCode := response.StatusCode
+	r, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+
+	apiErr := &APIError{StatusCode: statusCode, RequestID: responseRequestID(response)}
+	if err != nil {
+		apiErr.Message = fmt.Sprintf("Unable to read error response: %s", err.Error())
+		apiErr.readErr = err
+		return apiErr
+	}
+
+	if len(bytes.TrimSpace(r)) == 0 {
+		apiErr.Message = fmt.Sprintf("Request failed with status %d %s", statusCode, http.StatusText(statusCode))
+		return apiErr
+	}
+
+	// Only JSON bodies can provide an error in the format of the REST API,
+	// gateways returning plain text or HTML pages
+	mediaType := responseMediaType(response)
+	if mediaType == "" || isJSONMediaType(mediaType) {
+		var res struct {
+			Error Error `json:"error"`
+		}
+		if err = json.Unmarshal(r, &res); err == nil && res.Error.Message != "" {
+			apiErr.Message = res.Error.Message
+			apiErr.Code = res.Error.Code
+			return apiErr
+		}
+	}
+
+	text := string(r)
+	if mediaType == "text/html" {
+		text = htmlTagRegexp.ReplaceAllString(text, " ")
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	apiErr.Message = fmt.Sprintf("Unexpected error response: %s", truncate(text, maxErrorBodySnippet))
+
+	return apiErr
+}
+
+// htmlTagRegexp matches HTML tags, removed from HTML error pages
+var htmlTagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+// responseMediaType returns the media type of a response, or an empty
+// string if not provided or malformed
+func responseMediaType(response *http.Response) string {
+	mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// isJSONMediaType returns true if a media type is a JSON media type
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// checkJSONResponse checks the content type of a response expected to provide
+// JSON data. When a session is no more valid, alien4cloud can redirect requests
+// to its HTML login page, in which case an error matching ErrNotAuthenticated
+// is returned
+func checkJSONResponse(response *http.Response) error {
+	contentType := response.Header.Get("Content-Type")
+	if contentType == "" {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return errors.Wrapf(err, "Unexpected content type %q in response", contentType)
+	}
+	switch {
+	case isJSONMediaType(mediaType):
+		return nil
+	case mediaType == "text/html":
+		return errors.Wrapf(ErrNotAuthenticated,
+			"Received an HTML page instead of JSON data, the session is probably no more valid")
+	}
+	return errors.Errorf("Unexpected content type %q in response, expected JSON data", contentType)
+}
+
+// redirectedToLogin returns true if a response to a request sent to
+// requestURL is a redirect to the login page, or is the login page reached
+// after following such a redirect, as done by some deployments when the
+// session is no more valid
+func redirectedToLogin(response *http.Response, requestURL *url.URL) bool {
+	if response.StatusCode >= 300 && response.StatusCode < 400 {
+		location := response.Header.Get("Location")
+		if location == "" {
+			return false
+		}
+		locationURL, err := requestURL.Parse(location)
+		return err == nil && isLoginURL(locationURL)
+	}
+	// The request of a response is the last request sent when following redirects
+	if response.Request == nil || response.Request.URL.Path == requestURL.Path {
+		return false
+	}
+	return isLoginURL(response.Request.URL)
+}
+
+// isLoginURL returns true if a URL is the URL of a login page
+func isLoginURL(u *url.URL) bool {
+	for _, s := range []string{u.Path, u.Fragment} {
+		if strings.Contains(strings.ToLower(path.Base(s)), "login") {
+			return true
+		}
 	}
+	return false
+}
 
-	json.Unmarshal(r, &res)
+// decodeObject decodes a JSON object from decoder, calling fn on each key,
+// fn being expected to decode the value of this key. A null value is
+// considered as an empty object
+func decodeObject(decoder *json.Decoder, fn func(key string) error) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return errors.Errorf("Expected a JSON object, got %v", token)
+	}
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return errors.Errorf("Expected a JSON object key, got %v", token)
+		}
+		if err = fn(key); err != nil {
+			return err
+		}
+	}
+	// Closing delimiter
+	_, err = decoder.Token()
+	return err
+}
 
-	return errors.New(res.Error.Message)
+// skipValue decodes the next JSON value from decoder and ignores it
+func skipValue(decoder *json.Decoder) error {
+	var value json.RawMessage
+	return decoder.Decode(&value)
+}
+
+// getNotFoundError returns an APIError built from the status code and body
+// of a response, matching the sentinel error notFound if the resource
+// requested was not found
+func getNotFoundError(response *http.Response, notFound error) error {
+	err := getError(response)
+	if response.StatusCode == http.StatusNotFound {
+		err.(*APIError).kind = notFound
+	}
+	return err
+}
+
+// truncate returns at most maxLen bytes of a string, appending an ellipsis
+// when the string was truncated
+func truncate(s string, maxLen int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
+
+// orchestratorPath returns the path of an orchestrator in the REST API
+func orchestratorPath(restPrefix, orchestratorName string) string {
+	return restPrefix + "/orchestrators/" + url.PathEscape(orchestratorName)
+}
+
+// queryPath returns the path of a resources usage query in the REST API
+func queryPath(restPrefix, queryID string) string {
+	return restPrefix + "/orchestrators/" + escapeQueryID(queryID)
+}
+
+// escapeQueryID escapes each segment of a query ID of the form
+// <orchestrator>/infra_usage/<collector>/tasks/<id>, so that it can be used
+// in a request path
+func escapeQueryID(queryID string) string {
+	segments := strings.Split(queryID, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// queryIDFromHRef returns the ID of a query from its href, which can be either
+// a path or an absolute URL, possibly under the path of the alien4cloud URL.
+// Segments of the query ID are unescaped
+func queryIDFromHRef(href, restPrefix string) string {
+	path := href
+	if u, err := url.Parse(href); err == nil {
+		path = u.EscapedPath()
+	}
+	if i := strings.Index(path, restPrefix+"/orchestrators/"); i >= 0 {
+		path = path[i+len(restPrefix+"/orchestrators/"):]
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segments[i] = unescaped
+		}
+	}
+	return strings.Join(segments, "/")
 }
 
 // ------------------------------------------
 // Implementation of http.CookieJar interface
 // ------------------------------------------
 
-// jar structure used tO implement http.CookieJar interface
+// jar structure used tO implement http.CookieJar interface.
+// It honors the Domain, Path, Secure, Expires and Max-Age attributes
+// defined in RFC 6265. Cookies are stored by host for host-only cookies,
+// and by domain prefixed by a dot for domain cookies
 type jar struct {
-	lk      sync.Mutex
+	lk      sync.RWMutex
 	cookies map[string][]*http.Cookie
 }
 
@@ -60,14 +278,206 @@ func newJar() *jar {
 // given URL.  It may or may not choose to save the cookies, depending
 // on the jar's policy and implementation.
 func (jar *jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host := strings.ToLower(u.Hostname())
+	now := time.Now()
+
 	jar.lk.Lock()
-	jar.cookies[u.Host] = cookies
-	jar.lk.Unlock()
+	defer jar.lk.Unlock()
+
+	for _, c := range cookies {
+		cookie := *c
+
+		key := host
+		if cookie.Domain != "" {
+			domain := strings.ToLower(strings.TrimPrefix(cookie.Domain, "."))
+			if !domainMatch(host, domain) {
+				// A server can't set cookies for another domain
+				continue
+			}
+			key = "." + domain
+			cookie.Domain = domain
+		}
+
+		if cookie.Path == "" || !strings.HasPrefix(cookie.Path, "/") {
+			cookie.Path = defaultCookiePath(u.Path)
+		}
+
+		// Max-Age has precedence over Expires
+		expired := false
+		if cookie.MaxAge < 0 {
+			expired = true
+		} else if cookie.MaxAge > 0 {
+			cookie.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+			cookie.MaxAge = 0
+		} else if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			expired = true
+		}
+
+		// Replacing or removing a cookie with the same name and path
+		var stored []*http.Cookie
+		for _, existing := range jar.cookies[key] {
+			if existing.Name != cookie.Name || existing.Path != cookie.Path {
+				stored = append(stored, existing)
+			}
+		}
+		if !expired {
+			stored = append(stored, &cookie)
+		}
+		if len(stored) == 0 {
+			delete(jar.cookies, key)
+		} else {
+			jar.cookies[key] = stored
+		}
+	}
 }
 
 // Cookies returns the cookies to send in a request for the given URL.
 // It is up to the implementation to honor the standard cookie use
 // restrictions such as in RFC 6265.
 func (jar *jar) Cookies(u *url.URL) []*http.Cookie {
-	return jar.cookies[u.Host]
+	jar.lk.RLock()
+	defer jar.lk.RUnlock()
+
+	selected := jar.selectCookies(u, time.Now())
+
+	// Cookies with longer paths are listed first
+	sort.SliceStable(selected, func(i, j int) bool {
+		return len(selected[i].Path) > len(selected[j].Path)
+	})
+
+	// Returning copies so that callers can't modify cookies stored in the jar
+	var result []*http.Cookie
+	for _, c := range selected {
+		result = append(result, &http.Cookie{Name: c.Name, Value: c.Value})
+	}
+	return result
+}
+
+// expiry returns the earliest expiry time of cookies sent in a request for
+// the given URL. It returns false if no such cookie has an expiry time
+func (jar *jar) expiry(u *url.URL) (time.Time, bool) {
+	jar.lk.RLock()
+	defer jar.lk.RUnlock()
+
+	var expiry time.Time
+	for _, c := range jar.selectCookies(u, time.Now()) {
+		if !c.Expires.IsZero() && (expiry.IsZero() || c.Expires.Before(expiry)) {
+			expiry = c.Expires
+		}
+	}
+	return expiry, !expiry.IsZero()
+}
+
+// selectCookies returns the cookies stored in the jar, not yet expired at
+// time now, to send in a request for the given URL. The jar lock must be held
+func (jar *jar) selectCookies(u *url.URL, now time.Time) []*http.Cookie {
+	host := strings.ToLower(u.Hostname())
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	secure := u.Scheme == "https"
+
+	var selected []*http.Cookie
+	for key, cookies := range jar.cookies {
+		if key != host && !(strings.HasPrefix(key, ".") && domainMatch(host, key[1:])) {
+			continue
+		}
+		for _, c := range cookies {
+			if !c.Expires.IsZero() && !c.Expires.After(now) {
+				continue
+			}
+			if c.Secure && !secure {
+				continue
+			}
+			if !pathMatch(path, c.Path) {
+				continue
+			}
+			selected = append(selected, c)
+		}
+	}
+	return selected
+}
+
+// domainMatch returns true if host domain-matches domain as defined in RFC 6265
+func domainMatch(host, domain string) bool {
+	if host == domain {
+		return true
+	}
+	return net.ParseIP(host) == nil && strings.HasSuffix(host, "."+domain)
+}
+
+// pathMatch returns true if requestPath path-matches cookiePath as defined in RFC 6265
+func pathMatch(requestPath, cookiePath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath returns the default path of a cookie received
+// for a request URL path, as defined in RFC 6265
+func defaultCookiePath(requestPath string) string {
+	i := strings.LastIndex(requestPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+// save saves cookies in a file.
+// Cookies are first written in a temporary file which is then renamed, so
+// that concurrent processes sharing this file never read a partial content
+func (jar *jar) save(path string) error {
+	jar.lk.RLock()
+	b, err := json.Marshal(jar.cookies)
+	jar.lk.RUnlock()
+	if err != nil {
+		return errors.Wrapf(err, "Cannot encode cookies")
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Wrapf(err, "Cannot create temporary cookies file")
+	}
+	_, err = tmpFile.Write(b)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpFile.Name(), 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return errors.Wrapf(err, "Cannot write cookies file %s", path)
+	}
+	return nil
+}
+
+// load loads cookies previously saved in a file.
+// A file that doesn't exist yet is not considered as an error
+func (jar *jar) load(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	cookies := make(map[string][]*http.Cookie)
+	if err = json.Unmarshal(b, &cookies); err != nil {
+		return errors.Wrapf(err, "Cannot decode cookies file %s", path)
+	}
+
+	jar.lk.Lock()
+	jar.cookies = cookies
+	jar.lk.Unlock()
+	return nil
 }
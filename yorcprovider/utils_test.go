@@ -0,0 +1,76 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestJarConcurrentLoginsAndRequests(t *testing.T) {
+	var lk sync.Mutex
+	logins := 0
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			lk.Lock()
+			logins++
+			session := fmt.Sprintf("session-%d", logins)
+			lk.Unlock()
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: session, Path: "/"})
+			return
+		}
+		writeTestData(t, w, http.StatusOK, map[string]interface{}{
+			"orchestrators": []Orchestrator{{Name: "yorc"}},
+		})
+	}))
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := client.Login(); err != nil {
+				t.Errorf("Login() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := client.OrchestratorService().GetOrchestrators(); err != nil {
+				t.Errorf("GetOrchestrators() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestJarCookiesReturnsCopies(t *testing.T) {
+	u, _ := url.Parse("http://a4c.example.com/rest")
+	jar := newJar()
+	jar.SetCookies(u, []*http.Cookie{{Name: "JSESSIONID", Value: "1", Path: "/"}})
+
+	cookies := jar.Cookies(u)
+	if len(cookies) != 1 || cookies[0].Value != "1" {
+		t.Fatalf("Cookies() = %v, want the session cookie", cookies)
+	}
+	cookies[0].Value = "modified"
+
+	if got := jar.Cookies(u)[0].Value; got != "1" {
+		t.Errorf("Cookie stored in the jar was modified to %q by a caller", got)
+	}
+}
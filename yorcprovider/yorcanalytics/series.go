@@ -0,0 +1,97 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package yorcanalytics provides helpers analyzing resources usage collected
+// over time by a Yorc Provider client, like trends used for capacity planning.
+//
+// Usage:
+//
+//	var series yorcanalytics.UsageSeries
+//	series.Add(time.Now(), collection)
+//	...
+//	points, err := series.Trend("hosts.cpus")
+package yorcanalytics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/laurentganne/yorc-provider-go-client/v1/yorcprovider"
+)
+
+// UsageSeries accumulates usage collections with the time they were collected.
+// The zero value is an empty series ready to use. It is safe for concurrent use
+type UsageSeries struct {
+	lk      sync.Mutex
+	samples []sample
+}
+
+// sample is a usage collection collected at a given time
+type sample struct {
+	time       time.Time
+	collection *yorcprovider.UsageCollection
+}
+
+// TrendPoint is the value of a usage at a given time
+type TrendPoint struct {
+	Time time.Time
+	// Value is the sum of numeric values found at the key path
+	Value float64
+	// Count is the number of numeric values summed
+	Count int
+}
+
+// Add adds a usage collection collected at time t to the series
+func (s *UsageSeries) Add(t time.Time, collection *yorcprovider.UsageCollection) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	s.samples = append(s.samples, sample{time: t, collection: collection})
+}
+
+// Len returns the number of usage collections in the series
+func (s *UsageSeries) Len() int {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	return len(s.samples)
+}
+
+// Trend returns the value at a dotted key path of usage collections of the
+// series, sorted by time. Values are computed like yorcprovider.SumNumeric,
+// summing numeric values found in lists. Collections providing no numeric
+// value at this key path are skipped
+func (s *UsageSeries) Trend(keyPath string) ([]TrendPoint, error) {
+	s.lk.Lock()
+	samples := append([]sample(nil), s.samples...)
+	s.lk.Unlock()
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].time.Before(samples[j].time)
+	})
+
+	var points []TrendPoint
+	for _, smp := range samples {
+		value, count, err := yorcprovider.SumNumeric(smp.collection, keyPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Cannot compute trend of %s", keyPath)
+		}
+		if count == 0 {
+			continue
+		}
+		points = append(points, TrendPoint{Time: smp.time, Value: value, Count: count})
+	}
+	return points, nil
+}
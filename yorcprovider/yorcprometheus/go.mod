@@ -0,0 +1,5 @@
+module github.com/laurentganne/yorc-provider-go-client/v1/yorcprovider/yorcprometheus
+
+go 1.13
+
+require github.com/prometheus/client_golang v1.10.0
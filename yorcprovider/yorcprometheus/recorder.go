@@ -0,0 +1,101 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package yorcprometheus provides a Prometheus implementation of the
+// yorcprovider.MetricsRecorder interface, recording the count of requests
+// sent by a Yorc Provider client and their latency.
+//
+// Usage:
+//
+//	recorder := yorcprometheus.NewRecorder("myapp")
+//	prometheus.MustRegister(recorder)
+//	client, err := yorcprovider.New(url,
+//		yorcprovider.WithCredentials(user, password),
+//		yorcprovider.WithMetrics(recorder))
+//
+// This package is a separate module, so that programs using the client
+// without this package don't depend on the Prometheus client library
+package yorcprometheus
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Recorder records metrics on requests sent by a Yorc Provider client.
+// It implements both yorcprovider.MetricsRecorder and prometheus.Collector
+type Recorder struct {
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+var _ prometheus.Collector = (*Recorder)(nil)
+
+// NewRecorder returns a Recorder whose metrics names are prefixed by namespace
+func NewRecorder(namespace string) *Recorder {
+	return &Recorder{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Subsystem: "yorc_provider_client",
+				Name:      "requests_total",
+				Help:      "Number of requests sent to alien4cloud by operation and status class.",
+			},
+			[]string{"operation", "status"},
+		),
+		latency: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: namespace,
+				Subsystem: "yorc_provider_client",
+				Name:      "request_duration_seconds",
+				Help:      "Latency of requests sent to alien4cloud by operation.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"operation"},
+		),
+	}
+}
+
+// ObserveRequest records a request sent to alien4cloud
+func (r *Recorder) ObserveRequest(operation string, statusCode int, duration time.Duration) {
+	r.requests.WithLabelValues(operation, statusClass(statusCode)).Inc()
+	r.latency.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// Describe implements prometheus.Collector
+func (r *Recorder) Describe(ch chan<- *prometheus.Desc) {
+	r.requests.Describe(ch)
+	r.latency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (r *Recorder) Collect(ch chan<- prometheus.Metric) {
+	r.requests.Collect(ch)
+	r.latency.Collect(ch)
+}
+
+// statusClass returns the class of a status code, like 2xx, or error
+// when the request failed without any response
+func statusClass(statusCode int) string {
+	if statusCode <= 0 {
+		return "error"
+	}
+	if statusCode < 100 || statusCode > 599 {
+		return strconv.Itoa(statusCode)
+	}
+	return fmt.Sprintf("%dxx", statusCode/100)
+}
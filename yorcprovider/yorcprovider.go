@@ -19,28 +19,65 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net"
 	"net/http"
 	"net/url"
+	"path"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/goware/urlx"
 	"github.com/pkg/errors"
 )
 
-// Client is the client interface to the Yorc Provider
+// Client is the client interface to the Yorc Provider.
+//
+// A Client is safe for concurrent use by multiple goroutines, and should be
+// shared rather than created per request: services returned by a Client share
+// its connections, cookies and session. When concurrent requests get an
+// unauthorized response, a single new login is done, other requests waiting
+// for it before being sent again. Hooks provided with WithLogger and WithMetrics
+// are called concurrently and must be safe for concurrent use
 type Client interface {
 	Login() error
 	Logout() error
+	// LoginWithContext logs in with a Context that can be canceled
+	LoginWithContext(ctx context.Context) error
+	// LogoutWithContext logs out with a Context that can be canceled
+	LogoutWithContext(ctx context.Context) error
+	SaveCookies(path string) error
+	// Close releases resources held by the client, closing idle connections.
+	// The client can't be used anymore once closed
+	Close() error
+	// GetPluginVersion returns the version of the yorc collector plugin
+	GetPluginVersion() (string, error)
+	// GetPluginVersionWithContext returns the version of the yorc collector plugin
+	// with a Context that can be canceled
+	GetPluginVersionWithContext(ctx context.Context) (string, error)
+	// Ping checks alien4cloud can be reached and the client authenticated
+	Ping(ctx context.Context) error
+	// SessionExpiry returns the time at which the current session expires,
+	// or false if unknown
+	SessionExpiry() (time.Time, bool)
+	// RESTPrefix returns the path prefix of the REST API used by the client
+	RESTPrefix() string
+	// BuildOrchestratorPath returns the path of an orchestrator in the REST API
+	BuildOrchestratorPath(orchestratorName string) string
 	OrchestratorService() OrchestratorService
+	LocationService() LocationService
 	UsageCollectorService() UsageCollectorService
 }
 
+// QueryStatus is the status of a resources usage query
+type QueryStatus string
+
+// Query statuses are untyped constants, so that they can be compared to
+// the string status of a UsageCollection as well as to a QueryStatus
 const (
 	// QueryStatusInitial is the initial status of a qurery
 	QueryStatusInitial = "INITIAL"
@@ -54,20 +91,94 @@ const (
 	QueryStatusCanceled = "CANCELED"
 )
 
+// IsTerminal returns true if the status is a final status, the query
+// being done, failed or canceled
+func (s QueryStatus) IsTerminal() bool {
+	return s == QueryStatusDone || s == QueryStatusFailed || s == QueryStatusCanceled
+}
+
 const (
-	yorcProviderRESTPrefix = "/rest/yorc-collector-plugin/latest"
+	// DefaultRESTPrefix is the default path prefix of the yorc collector plugin REST API,
+	// targeting the latest version of the plugin
+	DefaultRESTPrefix = "/rest/yorc-collector-plugin/latest"
+	// DefaultUserAgent is the default User-Agent header of requests sent to alien4cloud
+	DefaultUserAgent = "yorc-provider-go-client/v1"
+	// DefaultMaxIdleConnsPerHost is the default maximum number of idle connections
+	// kept open to alien4cloud
+	DefaultMaxIdleConnsPerHost = 10
+	// DefaultMaxConcurrency is the default maximum number of operations
+	// run concurrently by helpers processing a batch of items
+	DefaultMaxConcurrency = 8
+	// DefaultDialTimeout is the default maximum time to wait for a connection to alien4cloud
+	DefaultDialTimeout = 30 * time.Second
+	// DefaultKeepAlive is the default interval between keep-alive probes of connections
+	DefaultKeepAlive = 30 * time.Second
+	// DefaultTLSHandshakeTimeout is the default maximum time to wait for a TLS handshake
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+	// DefaultMaxResponseSize is the default maximum size in bytes of response bodies
+	DefaultMaxResponseSize = 256 << 20
 )
 
-// NewClient instanciates and returns Client
-func NewClient(a4cURL string, user string, password string, caFile string, skipSecure bool) (Client, error) {
-	a4cAPI := strings.TrimRight(a4cURL, "/")
+// NewClient instanciates and returns Client.
+// As before options were introduced, a certificate authority file must be
+// provided for an https URL unless skipSecure is true, while New uses the
+// system certificate authorities by default
+//
+// Deprecated: use New, providing credentials and TLS settings as options
+func NewClient(a4cURL string, user string, password string, caFile string, skipSecure bool, opts ...ClientOption) (Client, error) {
+	m := schemeRegexp.FindStringSubmatch(strings.TrimSpace(a4cURL))
+	if m != nil && strings.EqualFold(m[1], "https") && caFile == "" && !skipSecure {
+		return nil, errors.Errorf("You must provide a certificate authority file in TLS verify mode")
+	}
+	clientOpts := []ClientOption{WithCredentials(user, password)}
+	if caFile != "" {
+		clientOpts = append(clientOpts, WithCAFile(caFile))
+	}
+	if skipSecure {
+		clientOpts = append(clientOpts, WithInsecureSkipVerify())
+	}
+	return New(a4cURL, append(clientOpts, opts...)...)
+}
+
+// schemeRegexp matches the scheme of a URL
+var schemeRegexp = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*)://`)
+
+// New instanciates and returns a Client to the alien4cloud instance at a4cURL,
+// configured by options.
+// a4cURL can have a path, like https://host/alien4cloud, when alien4cloud is
+// served under a context path, but no query or fragment. Duplicate and
+// trailing slashes of this path are removed.
+// By default, no credentials are set, TLS certificates are verified using the
+// system certificate authorities, and requests have no timeout
+func New(a4cURL string, opts ...ClientOption) (Client, error) {
+	options := clientOptions{
+		restPrefix:          DefaultRESTPrefix,
+		maxConcurrency:      DefaultMaxConcurrency,
+		userAgent:           DefaultUserAgent,
+		maxIdleConnsPerHost: DefaultMaxIdleConnsPerHost,
+		dialTimeout:         DefaultDialTimeout,
+		keepAlive:           DefaultKeepAlive,
+		tlsHandshakeTimeout: DefaultTLSHandshakeTimeout,
+		maxResponseSize:     DefaultMaxResponseSize,
+	}
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			return nil, err
+		}
+	}
+
+	a4cAPI := strings.TrimRight(strings.TrimSpace(a4cURL), "/")
 
-	if m, _ := regexp.Match("^http[s]?://.*", []byte(a4cAPI)); !m {
+	if m := schemeRegexp.FindStringSubmatch(a4cAPI); m == nil {
 		a4cAPI = "http://" + a4cAPI
+	} else if scheme := strings.ToLower(m[1]); scheme != "http" && scheme != "https" {
+		return nil, errors.Errorf("Unsupported scheme %s in alien4cloud URL %s, expected http or https", m[1], a4cURL)
+	} else {
+		a4cAPI = scheme + a4cAPI[len(m[1]):]
 	}
 
 	var useTLS = true
-	if m, _ := regexp.Match("^http://.*", []byte(a4cAPI)); m {
+	if strings.HasPrefix(a4cAPI, "http://") {
 		useTLS = false
 	}
 
@@ -75,80 +186,195 @@ func NewClient(a4cURL string, user string, password string, caFile string, skipS
 	if err != nil {
 		return nil, errors.Wrapf(err, "Malformed alien4cloud URL: %s", a4cAPI)
 	}
+	// The login path and REST API prefix are appended to this URL, which can
+	// have a path when alien4cloud is served under a context path
+	if url.RawQuery != "" || url.Fragment != "" {
+		return nil, errors.Errorf("Alien4cloud URL %s must not have a query or fragment", a4cURL)
+	}
+	if url.Path != "" {
+		// Dot segments would change the meaning of the path once cleaned
+		for _, segment := range strings.Split(url.Path, "/") {
+			if segment == "." || segment == ".." {
+				return nil, errors.Errorf("Alien4cloud URL %s must not have a relative path, got %s", a4cURL, url.Path)
+			}
+		}
+		// Removing duplicate and trailing slashes
+		url.Path = strings.TrimRight(path.Clean(url.Path), "/")
+		a4cAPI = url.String()
+	}
 
 	a4chost, _, err := urlx.SplitHostPort(url)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Malformed alien4cloud URL %s", url)
 	}
 
-	tlsConfig := &tls.Config{ServerName: a4chost}
+	cookieJar := newJar()
+	if options.cookieFile != "" {
+		if err := cookieJar.load(options.cookieFile); err != nil {
+			return nil, errors.Wrapf(err, "Failed to load cookies from %s", options.cookieFile)
+		}
+	}
+
+	// Jar used by the HTTP client
+	var httpJar http.CookieJar = cookieJar
+	if options.cookieJar != nil {
+		if options.cookieFile != "" {
+			return nil, errors.New("Cookies can't be persisted in a file when using a cookie jar provided with WithCookieJar")
+		}
+		httpJar = options.cookieJar
+		cookieJar = nil
+	}
 
-	if useTLS {
-		if caFile == "" || skipSecure {
-			if skipSecure {
+	var httpClient *http.Client
+	if options.httpClient != nil {
+		// Using a copy not to modify the client provided
+		c := *options.httpClient
+		if options.cookieJar != nil || c.Jar == nil {
+			c.Jar = httpJar
+		} else if options.cookieFile != "" {
+			return nil, errors.New("Cookies can't be persisted in a file when using an HTTP client having its own cookie jar")
+		} else {
+			cookieJar = nil
+		}
+		httpClient = &c
+	} else {
+		serverName := a4chost
+		if options.tlsServerName != "" {
+			serverName = options.tlsServerName
+		}
+		tlsConfig := &tls.Config{ServerName: serverName}
+
+		if useTLS {
+			if options.skipSecure {
 				tlsConfig.InsecureSkipVerify = true
-			} else {
-				return nil, errors.Errorf("You must provide a certificate authority file in TLS verify mode")
+			} else if options.caFile != "" {
+				certPool := x509.NewCertPool()
+				caCert, err := ioutil.ReadFile(options.caFile)
+				if err != nil {
+					return nil, errors.Wrapf(err, "Failed to read certificate authority file")
+				}
+				if !certPool.AppendCertsFromPEM(caCert) {
+					return nil, errors.Errorf("%q is not a valid certificate authority.", caCert)
+				}
+				tlsConfig.RootCAs = certPool
 			}
 		}
 
-		if !skipSecure {
-			certPool := x509.NewCertPool()
-			caCert, err := ioutil.ReadFile(caFile)
-			if err != nil {
-				return nil, errors.Wrapf(err, "Failed to read certificate authority file")
-			}
-			if !certPool.AppendCertsFromPEM(caCert) {
-				return nil, errors.Errorf("%q is not a valid certificate authority.", caCert)
-			}
-			tlsConfig.RootCAs = certPool
+		dialContext := options.dialContext
+		if dialContext == nil {
+			dialContext = (&net.Dialer{
+				Timeout:   options.dialTimeout,
+				KeepAlive: options.keepAlive,
+			}).DialContext
 		}
-	}
 
-	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		Dial: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).Dial,
-		TLSHandshakeTimeout: 10 * time.Second,
-		TLSClientConfig:     tlsConfig,
-	}
+		tr := &http.Transport{
+			Proxy:               proxyFunc(options.proxyURL, options.noProxy),
+			DialContext:         dialContext,
+			TLSHandshakeTimeout: options.tlsHandshakeTimeout,
+			TLSClientConfig:     tlsConfig,
+			MaxIdleConns:        options.maxIdleConns,
+			MaxIdleConnsPerHost: options.maxIdleConnsPerHost,
+			IdleConnTimeout:     options.idleConnTimeout,
+		}
 
-	restClient := restClient{
-		Client: &http.Client{
+		httpClient = &http.Client{
 			Transport:     tr,
 			CheckRedirect: nil,
-			Jar:           newJar(),
-			Timeout:       0},
-		baseURL:  a4cAPI,
-		username: user,
-		password: password,
+			Jar:           httpJar,
+			Timeout:       options.timeout}
+	}
+
+	restClient := &restClient{
+		Client:         httpClient,
+		baseURL:        a4cAPI,
+		restPrefix:     options.restPrefix,
+		maxConcurrency: options.maxConcurrency,
+		credentials:    options.credentials,
+		jar:            cookieJar,
+		cookieFile:     options.cookieFile,
+		userAgent:      options.userAgent,
+		defaultHeaders: options.defaultHeaders,
+		logger:         options.logger,
+		metrics:        options.metrics,
+		limiter:        options.limiter,
+		autoLogin:      options.autoLogin,
+		basicAuth:      options.basicAuth,
+		maxRespSize:    options.maxResponseSize,
+		strictDecoding: options.strictDecoding,
+		baseCtx:        options.baseCtx,
+		maxRetries:     options.maxRetries,
+		retryBackoff:   options.retryBackoff,
+		onRetry:        options.onRetry,
+		session:        &session{},
+		debug:          debugEnabled(),
+		done:           make(chan struct{}),
+	}
+	var tracker *queryTracker
+	if options.maxTrackedQueries > 0 {
+		tracker = newQueryTracker(options.maxTrackedQueries)
 	}
 	return &yorcProviderClient{
-		client:                restClient,
-		orchestratorService:   &orchestratorService{restClient},
-		usageCollectorService: &usageCollectorService{restClient},
+		client:              restClient,
+		orchestratorService: &orchestratorService{restClient},
+		locationService:     &locationService{restClient},
+		usageCollectorService: &usageCollectorService{
+			client:             restClient,
+			tracker:            tracker,
+			validateQueries:    options.validateQueries,
+			defaultQueryParams: options.defaultQueryParams,
+			cleanupQueries:     options.cleanupQueries,
+		},
 	}, nil
 }
 
-// Login login to alien4cloud
+// Login login to alien4cloud.
+// When cookies are persisted in a file and a session cookie was loaded from
+// this file, the login is skipped, a new login being done if the session
+// turns out to be no more valid
 func (c *yorcProviderClient) Login() error {
-	return c.client.login()
+	return c.LoginWithContext(context.Background())
+}
+
+// LoginWithContext login to alien4cloud with a Context that can be canceled
+func (c *yorcProviderClient) LoginWithContext(ctx context.Context) error {
+	if c.client.basicAuth {
+		return nil
+	}
+	if c.client.cookieFile != "" && c.client.hasSession() {
+		return nil
+	}
+	return c.client.login(withRequestID(c.client.requestContext(ctx)))
+}
+
+// SaveCookies saves the session cookies in a file
+func (c *yorcProviderClient) SaveCookies(path string) error {
+	if c.client.jar == nil {
+		return errors.New("Cookies can't be saved when using a cookie jar provided by the caller")
+	}
+	return c.client.jar.save(path)
 }
 
 // Logout log out from alien4cloud
 func (c *yorcProviderClient) Logout() error {
-	request, err := http.NewRequest("POST", fmt.Sprintf("%s/logout", c.client.baseURL), nil)
+	return c.LogoutWithContext(context.Background())
+}
+
+// LogoutWithContext log out from alien4cloud with a Context that can be canceled
+func (c *yorcProviderClient) LogoutWithContext(ctx context.Context) error {
+	if c.client.basicAuth {
+		return nil
+	}
+	request, err := http.NewRequestWithContext(withRequestID(c.client.requestContext(ctx)), "POST", fmt.Sprintf("%s/logout", c.client.baseURL), nil)
 	if err != nil {
-		log.Panic(err)
+		return errors.Wrapf(err, "Cannot create a logout request")
 	}
 	request.Header.Add("Accept", "application/json")
 	request.Header.Set("Connection", "close")
 
 	request.Close = true
 
-	response, err := c.client.Client.Do(request)
+	response, err := c.client.send("Logout", request, nil)
 
 	if err != nil {
 		return err
@@ -156,114 +382,326 @@ func (c *yorcProviderClient) Logout() error {
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return getError(response.Body)
+		return getError(response)
 	}
 
 	return nil
 }
 
+// Close releases resources held by the client, closing idle connections and
+// stopping background work. Requests sent once the client is closed fail with
+// ErrClientClosed
+func (c *yorcProviderClient) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.client.done)
+		c.client.Client.CloseIdleConnections()
+	})
+	return nil
+}
+
+// SessionExpiry returns the time at which the current alien4cloud session
+// expires, as provided by the Expires or Max-Age attributes of session cookies.
+// It returns false when there is no session or alien4cloud doesn't provide
+// an expiry time
+func (c *yorcProviderClient) SessionExpiry() (time.Time, bool) {
+	return c.client.sessionExpiry()
+}
+
+// RESTPrefix returns the path prefix of the REST API used by the client,
+// as configured by WithRESTPrefix
+func (c *yorcProviderClient) RESTPrefix() string {
+	return c.client.prefix()
+}
+
+// BuildOrchestratorPath returns the path of an orchestrator in the REST API,
+// like <prefix>/orchestrators/<name>, the name being escaped.
+// Paths of other resources of this orchestrator start with this path
+func (c *yorcProviderClient) BuildOrchestratorPath(orchestratorName string) string {
+	return orchestratorPath(c.client.prefix(), orchestratorName)
+}
+
 // OrchestratorService retrieves the Orchestrator Service
 func (c *yorcProviderClient) OrchestratorService() OrchestratorService {
 	return c.orchestratorService
 }
 
+// LocationService retrieves the Location Service
+func (c *yorcProviderClient) LocationService() LocationService {
+	return c.locationService
+}
+
 // UsageCollectorService retrieves the Orchestrator Service
 func (c *yorcProviderClient) UsageCollectorService() UsageCollectorService {
 	return c.usageCollectorService
 }
 
+// restAPI is the interface used by services to send requests to the REST API,
+// allowing to test services with a fake implementation
+type restAPI interface {
+	// do requests the alien4cloud rest api
+	do(operation string, method string, path string, body []byte, headers []Header) (*http.Response, error)
+	// doWithContext requests the alien4cloud rest api with a Context that can be canceled
+	doWithContext(ctx context.Context, operation string, method string, path string, body []byte, headers []Header) (*http.Response, error)
+	// prefix returns the path prefix of the REST API
+	prefix() string
+	// concurrency returns the maximum number of operations run concurrently
+	// by batch helpers
+	concurrency() int
+	// unmarshal decodes the JSON body of a response
+	unmarshal(data []byte, v interface{}) error
+}
+
 type restClient struct {
 	*http.Client
-	baseURL  string
-	username string
-	password string
+	baseURL        string
+	restPrefix     string
+	maxConcurrency int
+	// credentials provides credentials used to log in
+	credentials    CredentialsProvider
+	jar            *jar
+	cookieFile     string
+	userAgent      string
+	defaultHeaders []Header
+	logger         Logger
+	metrics        MetricsRecorder
+	limiter        *rateLimiter
+	autoLogin      bool
+	// basicAuth is true when credentials are sent with HTTP Basic authentication
+	basicAuth bool
+	// maxRespSize is the default maximum size of response bodies
+	maxRespSize    int64
+	strictDecoding bool
+	// baseCtx is the base context of requests, if any
+	baseCtx      context.Context
+	maxRetries   int
+	retryBackoff time.Duration
+	onRetry      RetryFunc
+	// session serializes logins
+	session *session
+	debug   bool
+	// done is closed when the client is closed, stopping background work
+	done chan struct{}
 }
 
 type yorcProviderClient struct {
-	client                restClient
+	client                *restClient
+	closeOnce             sync.Once
 	orchestratorService   *orchestratorService
+	locationService       *locationService
 	usageCollectorService *usageCollectorService
 }
 
-// do requests the alien4cloud rest api with a Context that can be canceled
-func (r *restClient) doWithContext(ctx context.Context, method string, path string, body []byte, headers []Header) (*http.Response, error) {
-
-	bodyBytes := bytes.NewBuffer(body)
-
-	// Create the request
-	var request *http.Request
-	var err error
-	if ctx == nil {
-		request, err = http.NewRequest(method, r.baseURL+path, bodyBytes)
-	} else {
+// do requests the alien4cloud rest api with a Context that can be canceled.
+//
+// The number of round trips of a call is bounded by a single budget: a call
+// sends at most maxRetries+1 requests, plus one request sent again after
+// a new login when the session is no more valid, and at most one login request,
+// done either by the auto-login or on an unauthorized response
+func (r *restClient) doWithContext(ctx context.Context, operation string, method string, path string, body []byte, headers []Header) (*http.Response, error) {
 
-		request, err = http.NewRequestWithContext(ctx, method, r.baseURL+path, bodyBytes)
-	}
+	// Requests sent by this call, including a login, have the same ID
+	ctx = withRequestID(r.requestContext(ctx))
 
+	// A login done by the auto-login uses the login allowed for this call
+	loggedIn, err := r.ensureSession(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Add header
-	for _, header := range headers {
-		request.Header.Add(header.Key, header.Value)
-	}
+	budget := r.maxRetries + 1
+	retries := 0
+	for attempt := 1; ; attempt++ {
+		sessionSeen := r.session.current()
 
-	response, err := r.Client.Do(request)
-	if err != nil {
-		return nil, err
-	}
-
-	// Cookie can potentially be expired. If we are unauthorized to send a request, we should try to login again.
-	if response.StatusCode == http.StatusForbidden {
-		err = r.login()
+		// Create the request
+		request, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, bytes.NewBuffer(body))
 		if err != nil {
 			return nil, err
 		}
 
-		bodyBytes = bytes.NewBuffer(body)
+		// Add header
+		for _, header := range headers {
+			request.Header.Add(header.Key, header.Value)
+		}
+		setRequestHeaders(request)
+		if r.basicAuth {
+			username, password, err := r.getCredentials(ctx)
+			if err != nil {
+				return nil, err
+			}
+			request.SetBasicAuth(username, password)
+		}
+
+		response, err := r.send(operation, request, body)
 
-		request, err := http.NewRequest(method, r.baseURL+path, bodyBytes)
-		if err != nil {
-			return nil, err
+		// Cookie can potentially be expired. If we are unauthorized to send a request, we should try to login again.
+		// Some deployments redirect requests to the login page instead.
+		// There is no session to refresh when using basic authentication
+		if err == nil && !loggedIn && !r.basicAuth &&
+			(response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusUnauthorized ||
+				redirectedToLogin(response, request.URL)) {
+			response.Body.Close()
+			err = r.relogin(ctx, sessionSeen)
+			if err != nil {
+				return nil, err
+			}
+			loggedIn = true
+			// One more request is allowed to send the request again once logged in
+			budget++
+			continue
 		}
 
-		for _, header := range headers {
-			request.Header.Add(header.Key, header.Value)
+		if attempt >= budget || !shouldRetry(method, response, err) {
+			return response, err
 		}
 
-		response, err := r.Client.Do(request)
-		if err != nil {
-			return nil, err
+		delay := r.retryDelay(attempt-1, response)
+		retries++
+		if r.onRetry != nil {
+			retryErr := err
+			if response != nil {
+				// The error response is provided to the hook, consuming its body
+				retryErr = getError(response)
+			}
+			r.onRetry(retries, RequestInfo{Method: method, Path: request.URL.RequestURI(), Body: body}, retryErr, delay)
+		} else if response != nil {
+			response.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "Stopped waiting before sending request again")
+		case <-time.After(delay):
 		}
+	}
+}
+
+// send sends a request to alien4cloud, notifying the logger and metrics recorder
+// if any, and logging the request on stderr in debug mode.
+// The operation is the name of the client method sending this request.
+// The body provided is the body to log, from which credentials were redacted
+func (r *restClient) send(operation string, request *http.Request, body []byte) (*http.Response, error) {
+	select {
+	case <-r.done:
+		return nil, ErrClientClosed
+	default:
+	}
 
-		return response, nil
+	request.Header.Set("User-Agent", r.userAgent)
+	if requestID, ok := RequestIDFromContext(request.Context()); ok {
+		request.Header.Set(RequestIDHeader, requestID)
 	}
 
+	// Adding default headers, unless set on this request
+	presentKeys := make(map[string]bool)
+	for key := range request.Header {
+		presentKeys[key] = true
+	}
+	for _, header := range r.defaultHeaders {
+		if !presentKeys[http.CanonicalHeaderKey(header.Key)] {
+			request.Header.Add(header.Key, header.Value)
+		}
+	}
+
+	info := RequestInfo{
+		Method: request.Method,
+		Path:   request.URL.RequestURI(),
+		Body:   body,
+	}
+	if r.logger != nil {
+		r.logger.LogRequest(info)
+	}
+	if r.debug {
+		debugRequest(request, body)
+	}
+
+	if r.limiter != nil {
+		if err := r.limiter.wait(request.Context()); err != nil {
+			return nil, errors.Wrapf(err, "Stopped waiting for the rate limiter")
+		}
+	}
+
+	start := time.Now()
+	response, err := r.Client.Do(request)
+	duration := time.Since(start)
+
+	if response != nil {
+		response.Body = limitBody(response.Body, r.maxResponseSize(request.Context()))
+	}
+	if r.debug {
+		debugResponse(request, response, duration, err)
+	}
+	var statusCode int
+	if response != nil {
+		statusCode = response.StatusCode
+	}
+	if r.logger != nil {
+		r.logger.LogResponse(info, statusCode, duration, err)
+	}
+	if r.metrics != nil {
+		r.metrics.ObserveRequest(operation, statusCode, duration)
+	}
+	if err != nil {
+		// No response from the server, this is a transport-level failure
+		return nil, &NetworkError{
+			Operation: operation,
+			Method:    request.Method,
+			URL:       request.URL.String(),
+			RequestID: request.Header.Get(RequestIDHeader),
+			Err:       err,
+		}
+	}
 	return response, nil
 }
 
+// prefix returns the path prefix of the REST API
+func (r *restClient) prefix() string {
+	return r.restPrefix
+}
+
+// concurrency returns the maximum number of operations run concurrently by batch helpers
+func (r *restClient) concurrency() int {
+	return r.maxConcurrency
+}
+
+// unmarshal decodes the JSON body of a response. In strict decoding mode,
+// an error is returned if the body has fields unknown to v
+func (r *restClient) unmarshal(data []byte, v interface{}) error {
+	if !r.strictDecoding {
+		return json.Unmarshal(data, v)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
 // do requests the alien4cloud rest api
-func (r *restClient) do(method string, path string, body []byte, headers []Header) (*http.Response, error) {
+func (r *restClient) do(operation string, method string, path string, body []byte, headers []Header) (*http.Response, error) {
 
-	return r.doWithContext(nil, method, path, body, headers)
+	return r.doWithContext(nil, operation, method, path, body, headers)
 }
 
-// login to alien4cloud
-func (r *restClient) login() error {
+// doLogin sends a login request to alien4cloud
+func (r *restClient) doLogin(ctx context.Context) error {
+	username, password, err := r.getCredentials(ctx)
+	if err != nil {
+		return err
+	}
 	values := url.Values{}
-	values.Set("username", r.username)
-	values.Set("password", r.password)
+	values.Set("username", username)
+	values.Set("password", password)
 	values.Set("submit", "Login")
-	request, err := http.NewRequest("POST", fmt.Sprintf("%s/login", r.baseURL),
+	request, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/login", r.baseURL),
 		strings.NewReader(values.Encode()))
 	if err != nil {
-		log.Panic(err)
+		return errors.Wrapf(err, "Cannot create a login request")
 	}
 	request.Header.Add("Accept", "application/json")
 	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	setRequestHeaders(request)
 
-	response, err := r.Client.Do(request)
+	// The password must not appear in logs
+	values.Set("password", redacted)
+	response, err := r.send("Login", request, []byte(values.Encode()))
 
 	if err != nil {
 		return err
@@ -271,8 +709,86 @@ func (r *restClient) login() error {
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusOK {
-		return getError(response.Body)
+		apiErr := getError(response).(*APIError)
+		apiErr.kind = ErrNotAuthenticated
+		return apiErr
+	}
+
+	if r.cookieFile != "" {
+		if err := r.jar.save(r.cookieFile); err != nil {
+			return errors.Wrapf(err, "Failed to save cookies in %s", r.cookieFile)
+		}
 	}
 
 	return nil
 }
+
+// hasSession returns true if a session cookie not yet expired is available
+// for the alien4cloud URL
+func (r *restClient) hasSession() bool {
+	if r.jar == nil {
+		return false
+	}
+	u, err := url.Parse(r.baseURL)
+	if err != nil {
+		return false
+	}
+	// The jar doesn't return expired cookies
+	return len(r.jar.Cookies(u)) > 0
+}
+
+// getCredentials returns the credentials used to log in
+func (r *restClient) getCredentials(ctx context.Context) (string, string, error) {
+	if r.credentials == nil {
+		return "", "", nil
+	}
+	username, password, err := r.credentials.Credentials(ctx)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Unable to get credentials to log in to alien4cloud")
+	}
+	return username, password, nil
+}
+
+// sessionExpiry returns the expiry time of the session cookies stored for
+// the alien4cloud URL
+func (r *restClient) sessionExpiry() (time.Time, bool) {
+	if r.jar == nil {
+		return time.Time{}, false
+	}
+	u, err := url.Parse(r.baseURL)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return r.jar.expiry(u)
+}
+
+// String returns a description of the client, the password being redacted
+func (r *restClient) String() string {
+	// Credentials from other providers are not described, not to call them
+	username, password := "", ""
+	if static, ok := r.credentials.(StaticCredentials); ok {
+		username = static.User
+		if static.Password != "" {
+			password = redacted
+		}
+	}
+	return fmt.Sprintf("restClient{baseURL: %q, restPrefix: %q, username: %q, password: %q}",
+		r.baseURL, r.restPrefix, username, password)
+}
+
+// GoString returns a description of the client used by the %#v format,
+// the password being redacted
+func (r *restClient) GoString() string {
+	return r.String()
+}
+
+// String returns a description of the client, the password being redacted
+func (c *yorcProviderClient) String() string {
+	return fmt.Sprintf("yorcProviderClient{client: %s}", c.client.String())
+}
+
+// GoString returns a description of the client used by the %#v format,
+// the password being redacted
+func (c *yorcProviderClient) GoString() string {
+	return c.String()
+}
@@ -14,12 +14,22 @@
 
 package yorcprovider
 
+import "net/http"
+
 // Orchestrator holds properties describing an orchestrator
 type Orchestrator struct {
 	Name string `json:"name,omitempty"`
 	HRef string `json:"href,omitempty"`
 }
 
+// Location holds properties describing a location of an orchestrator:
+// its name, its type, and the name of its orchestrator
+type Location struct {
+	Name         string `json:"name,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Orchestrator string `json:"orchestrator,omitempty"`
+}
+
 // UsageCollector holds properties describing a Usage Collector: its id, and the plugin
 // implementing this collector
 type UsageCollector struct {
@@ -27,11 +37,80 @@ type UsageCollector struct {
 	Origin string `json:"origin,omitempty"`
 }
 
-// UsageCollection holds the status of a Resources usage query, and results when the
-// collection is done
+// CollectorCapability describes a Usage Collector as provided by the registry
+// of an orchestrator: its id, the plugin implementing this collector, and the
+// keys of query parameters it accepts, when provided by the registry
+type CollectorCapability struct {
+	ID         string   `json:"id,omitempty"`
+	Origin     string   `json:"origin,omitempty"`
+	Parameters []string `json:"parameters,omitempty"`
+}
+
+// QueryInfo holds properties describing a resources usage query: its ID,
+// the type and relation of the task performing the query, its href, and
+// its status when provided
+type QueryInfo struct {
+	ID     string      `json:"id,omitempty"`
+	Type   string      `json:"type,omitempty"`
+	Rel    string      `json:"rel,omitempty"`
+	HRef   string      `json:"href,omitempty"`
+	Status QueryStatus `json:"status,omitempty"`
+}
+
+// UsageCollection holds the status of a Resources usage query, the target and type
+// of this query, and results when the collection is done.
+// JSON tags match the representation of a query in the REST API responses
 type UsageCollection struct {
-	Status  string                 `json:"status,omitempty"`
-	Results map[string]interface{} `json:"results,omitempty"`
+	ID       string                 `json:"id,omitempty"`
+	TargetID string                 `json:"target_id,omitempty"`
+	Type     string                 `json:"type,omitempty"`
+	Status   string                 `json:"status,omitempty"`
+	Results  map[string]interface{} `json:"result_set,omitempty"`
+	// FailureReason is the error message provided when the status is QueryStatusFailed,
+	// empty for other statuses
+	FailureReason string `json:"error,omitempty"`
+	// Progress is the progress percentage of the collection, from 0 to 100,
+	// reported by some collectors while running, or -1 when unknown
+	Progress int `json:"progress"`
+}
+
+// QueryStatus returns the status of the query as a QueryStatus
+func (u *UsageCollection) QueryStatus() QueryStatus {
+	return QueryStatus(u.Status)
+}
+
+// usageCollectionData is the representation of a usage collection in REST API
+// responses, where the progress is an optional percentage which may have a
+// fractional part. Its Progress field, being less nested than the Progress
+// field of UsageCollection, is the one decoded from the progress key
+type usageCollectionData struct {
+	UsageCollection
+	Progress *float64 `json:"progress"`
+}
+
+// collection returns the usage collection decoded, Progress being set to -1
+// when not provided, and FailureReason being kept only for a failed query
+func (d *usageCollectionData) collection() *UsageCollection {
+	collection := d.UsageCollection
+	collection.Progress = progressPercent(d.Progress)
+	if collection.Status != QueryStatusFailed {
+		collection.FailureReason = ""
+	}
+	return &collection
+}
+
+// progressPercent returns a progress percentage between 0 and 100,
+// or -1 if no progress is provided
+func progressPercent(progress *float64) int {
+	switch {
+	case progress == nil:
+		return -1
+	case *progress < 0:
+		return 0
+	case *progress > 100:
+		return 100
+	}
+	return int(*progress)
 }
 
 // Header is the representation of an http header
@@ -40,8 +119,119 @@ type Header struct {
 	Value string
 }
 
+// ResponseMeta provides the status and headers of a response,
+// like custom headers added by a gateway
+type ResponseMeta struct {
+	StatusCode int
+	Header     http.Header
+}
+
+// set stores the status and headers of a response, if meta is not nil
+func (meta *ResponseMeta) set(response *http.Response) {
+	if meta == nil {
+		return
+	}
+	meta.StatusCode = response.StatusCode
+	meta.Header = response.Header
+}
+
+// RequestInfo describes a request sent to alien4cloud, credentials being redacted
+type RequestInfo struct {
+	Method string
+	// Path is the request URI, path and query
+	Path string
+	Body []byte
+}
+
 // Error is the representation of a yorc provider error
 type Error struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 }
+
+// SlurmUsage holds resources usage reported by a Slurm usage collector.
+// Fields absent from a collector response are left to their zero value
+type SlurmUsage struct {
+	NodesTotal      int                   `json:"nodes_total,omitempty"`
+	NodesAllocated  int                   `json:"nodes_allocated,omitempty"`
+	CPUsTotal       int                   `json:"cpus_total,omitempty"`
+	CPUsAllocated   int                   `json:"cpus_allocated,omitempty"`
+	MemoryTotal     int64                 `json:"memory_total,omitempty"`
+	MemoryAllocated int64                 `json:"memory_allocated,omitempty"`
+	JobsRunning     int                   `json:"jobs_running,omitempty"`
+	JobsPending     int                   `json:"jobs_pending,omitempty"`
+	Partitions      []SlurmPartitionUsage `json:"partitions,omitempty"`
+}
+
+// SlurmPartitionUsage holds resources usage of a Slurm partition.
+// Memory values are expressed in megabytes
+type SlurmPartitionUsage struct {
+	Name            string `json:"name,omitempty"`
+	NodesTotal      int    `json:"nodes_total,omitempty"`
+	NodesAllocated  int    `json:"nodes_allocated,omitempty"`
+	CPUsTotal       int    `json:"cpus_total,omitempty"`
+	CPUsAllocated   int    `json:"cpus_allocated,omitempty"`
+	MemoryTotal     int64  `json:"memory_total,omitempty"`
+	MemoryAllocated int64  `json:"memory_allocated,omitempty"`
+	JobsRunning     int    `json:"jobs_running,omitempty"`
+	JobsPending     int    `json:"jobs_pending,omitempty"`
+}
+
+// GoogleUsage holds resources usage reported by a Google Cloud usage collector.
+// Memory values are expressed in megabytes, disk sizes in gigabytes.
+// Fields absent from a collector response are left to their zero value
+type GoogleUsage struct {
+	Project   string `json:"project,omitempty"`
+	Instances int    `json:"instances,omitempty"`
+	VCPUs     int    `json:"vcpus,omitempty"`
+	Memory    int64  `json:"memory,omitempty"`
+	Disks     int    `json:"disks,omitempty"`
+	DiskSize  int64  `json:"disk_size,omitempty"`
+}
+
+// AWSUsage holds resources usage reported by an AWS usage collector.
+// Memory values are expressed in megabytes, disk sizes in gigabytes.
+// Fields absent from a collector response are left to their zero value
+type AWSUsage struct {
+	Account   string `json:"account,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Instances int    `json:"instances,omitempty"`
+	VCPUs     int    `json:"vcpus,omitempty"`
+	Memory    int64  `json:"memory,omitempty"`
+	Disks     int    `json:"disks,omitempty"`
+	DiskSize  int64  `json:"disk_size,omitempty"`
+}
+
+// HostStatus is the allocation status of a host in a hosts pool
+type HostStatus string
+
+const (
+	// HostStatusFree is the status of a host that can be allocated
+	HostStatusFree HostStatus = "free"
+	// HostStatusAllocated is the status of an allocated host
+	HostStatusAllocated HostStatus = "allocated"
+	// HostStatusError is the status of a host in error
+	HostStatusError HostStatus = "error"
+)
+
+// HostsPoolUsage holds the inventory of hosts reported by a Hosts Pool usage collector
+type HostsPoolUsage struct {
+	Hosts []HostsPoolHost `json:"hosts,omitempty"`
+}
+
+// HostsPoolHost holds the allocation status, labels and resources capacity
+// of a host in a hosts pool. Labels are kept as provided, nested values included
+type HostsPoolHost struct {
+	Name     string                 `json:"name,omitempty"`
+	Status   HostStatus             `json:"status,omitempty"`
+	Labels   map[string]interface{} `json:"labels,omitempty"`
+	Capacity HostCapacity           `json:"capacity,omitempty"`
+}
+
+// HostCapacity holds the resources capacity of a host.
+// Memory values are expressed in megabytes, disk sizes in gigabytes
+type HostCapacity struct {
+	CPUs   int   `json:"cpus,omitempty"`
+	Memory int64 `json:"memory,omitempty"`
+	Disk   int64 `json:"disk,omitempty"`
+}
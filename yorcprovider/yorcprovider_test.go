@@ -0,0 +1,241 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client sending requests to a test server calling handler.
+// The server must be closed by the caller
+func newTestClient(t *testing.T, handler http.Handler, opts ...ClientOption) (Client, *httptest.Server) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	opts = append([]ClientOption{WithHTTPClient(server.Client())}, opts...)
+	client, err := New(server.URL, opts...)
+	if err != nil {
+		server.Close()
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return client, server
+}
+
+// writeTestData writes a JSON response providing data as the REST API does
+func writeTestData(t *testing.T, w http.ResponseWriter, statusCode int, data interface{}) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"data": data}); err != nil {
+		t.Errorf("Failed to write response: %v", err)
+	}
+}
+
+// recordingLogger is a Logger recording requests logged
+type recordingLogger struct {
+	mu       sync.Mutex
+	requests []RequestInfo
+}
+
+func (l *recordingLogger) LogRequest(req RequestInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, req)
+}
+
+func (l *recordingLogger) LogResponse(req RequestInfo, statusCode int, duration time.Duration, err error) {
+}
+
+func TestPasswordIsNeverFormatted(t *testing.T) {
+	const password = "s3cr3t-passw0rd"
+	logger := &recordingLogger{}
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), WithCredentials("user", password), WithLogger(logger))
+	defer server.Close()
+
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if s := fmt.Sprintf(format, client); strings.Contains(s, password) {
+			t.Errorf("Sprintf(%q, client) = %s, containing the password", format, s)
+		}
+	}
+
+	if err := client.Login(); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if len(logger.requests) != 1 {
+		t.Fatalf("Logged %d requests, want 1", len(logger.requests))
+	}
+	req := logger.requests[0]
+	if !strings.HasSuffix(req.Path, "/login") {
+		t.Errorf("Logged request path = %s, want the login path", req.Path)
+	}
+	if body := string(req.Body); strings.Contains(body, password) || !strings.Contains(body, "username=user") {
+		t.Errorf("Logged login body = %s, want the username without the password", body)
+	}
+}
+
+func TestNewClientRequiresCAFileForHTTPS(t *testing.T) {
+	if _, err := NewClient("https://a4c.example.com", "user", "pass", "", false); err == nil {
+		t.Error("NewClient() without certificate authority file for an https URL succeeded, want an error")
+	}
+	if _, err := NewClient("https://a4c.example.com", "user", "pass", "", true); err != nil {
+		t.Errorf("NewClient() skipping verification error = %v", err)
+	}
+	if _, err := NewClient("http://a4c.example.com", "user", "pass", "", false); err != nil {
+		t.Errorf("NewClient() for an http URL error = %v", err)
+	}
+	if _, err := New("https://a4c.example.com", WithCredentials("user", "pass")); err != nil {
+		t.Errorf("New() using system certificate authorities error = %v", err)
+	}
+}
+
+func TestClientConcurrentUse(t *testing.T) {
+	var lk sync.Mutex
+	tasks := 0
+	client, server := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/login":
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "session", Path: "/"})
+		case r.Method == http.MethodPost:
+			lk.Lock()
+			tasks++
+			task := tasks
+			lk.Unlock()
+			w.Header().Set("Location", fmt.Sprintf("%s/orchestrators/yorc/infra_usage/slurm/tasks/%d", DefaultRESTPrefix, task))
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == DefaultRESTPrefix+"/orchestrators":
+			writeTestData(t, w, http.StatusOK, map[string]interface{}{
+				"orchestrators": []Orchestrator{{Name: "yorc"}},
+			})
+		default:
+			writeTestData(t, w, http.StatusOK, map[string]interface{}{"id": "1", "status": "DONE"})
+		}
+	}), WithCredentials("user", "password"))
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := client.OrchestratorService().GetOrchestrators(); err != nil {
+				t.Errorf("GetOrchestrators() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			service := client.UsageCollectorService()
+			queryID, err := service.Query("yorc", "slurm", "hpc", nil)
+			if err != nil {
+				t.Errorf("Query() error = %v", err)
+				return
+			}
+			if _, err = service.WaitForQueryCompletion(context.Background(), queryID.String(), time.Millisecond); err != nil {
+				t.Errorf("WaitForQueryCompletion() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNewWithBasePath(t *testing.T) {
+	const basePath = "/alien4cloud"
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch {
+		case r.URL.Path == basePath+"/login":
+			http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: "session", Path: "/"})
+		case r.Method == http.MethodPost:
+			w.Header().Set("Location", basePath+DefaultRESTPrefix+"/orchestrators/yorc/infra_usage/slurm/tasks/1")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			writeTestData(t, w, http.StatusOK, map[string]interface{}{
+				"orchestrators": []Orchestrator{{Name: "yorc"}},
+			})
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(server.URL+basePath+"/", WithHTTPClient(server.Client()), WithCredentials("user", "password"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err = client.Login(); err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if _, err = client.OrchestratorService().GetOrchestrators(); err != nil {
+		t.Fatalf("GetOrchestrators() error = %v", err)
+	}
+	queryID, err := client.UsageCollectorService().Query("yorc", "slurm", "hpc", nil)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if want := "yorc/infra_usage/slurm/tasks/1"; queryID.String() != want {
+		t.Errorf("Query() = %q, want %q", queryID, want)
+	}
+	want := []string{
+		basePath + "/login",
+		basePath + DefaultRESTPrefix + "/orchestrators",
+		basePath + DefaultRESTPrefix + "/orchestrators/yorc/infra_usage/slurm/hpc",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Request paths = %v, want %v", paths, want)
+	}
+
+	for _, a4cURL := range []string{
+		"http://a4c.example.com/alien4cloud?debug=true",
+		"http://a4c.example.com/alien4cloud#login",
+		"http://a4c.example.com/a4c/../alien4cloud",
+		"http://a4c.example.com/./alien4cloud",
+	} {
+		if _, err := New(a4cURL); err == nil {
+			t.Errorf("New(%q) succeeded, want an error", a4cURL)
+		}
+	}
+}
+
+func TestNewNormalizesBasePath(t *testing.T) {
+	tests := []struct {
+		a4cURL string
+		want   string
+	}{
+		{"https://a4c.example.com", "https://a4c.example.com"},
+		{"https://a4c.example.com/", "https://a4c.example.com"},
+		{"https://a4c.example.com/alien4cloud/", "https://a4c.example.com/alien4cloud"},
+		{"https://a4c.example.com//alien4cloud//", "https://a4c.example.com/alien4cloud"},
+		{"https://a4c.example.com/a4c//ui/alien4cloud", "https://a4c.example.com/a4c/ui/alien4cloud"},
+		{"a4c.example.com:8088/my%20alien4cloud", "http://a4c.example.com:8088/my%20alien4cloud"},
+	}
+	for _, tt := range tests {
+		client, err := New(tt.a4cURL)
+		if err != nil {
+			t.Errorf("New(%q) error = %v", tt.a4cURL, err)
+			continue
+		}
+		if got := client.(*yorcProviderClient).client.baseURL; got != tt.want {
+			t.Errorf("New(%q) base URL = %s, want %s", tt.a4cURL, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,445 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package yorcprovidertest provides a fake Yorc Provider client to test code
+// built on top of the yorcprovider package, without any alien4cloud instance.
+//
+// FakeClient is a yorcprovider.Client sending its requests to an in-memory
+// implementation of the REST API, backed by maps of orchestrators, locations, collectors
+// and queries:
+//
+//	fake := yorcprovidertest.NewFakeClient()
+//	fake.AddOrchestrator("Yorc")
+//	fake.AddUsageCollector("Yorc", yorcprovider.UsageCollector{ID: "slurm", Origin: "yorc-slurm-plugin"})
+//	// Queries are INITIAL on the first poll, RUNNING on the next two, then DONE
+//	fake.SetStatusSequence(yorcprovider.QueryStatusInitial, yorcprovider.QueryStatusRunning,
+//		yorcprovider.QueryStatusRunning, yorcprovider.QueryStatusDone)
+//	fake.SetResults(map[string]interface{}{"nodes_total": 10})
+//	// Testing code using fake as a yorcprovider.Client
+package yorcprovidertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/laurentganne/yorc-provider-go-client/v1/yorcprovider"
+)
+
+// fakeURL is the URL of the fake alien4cloud instance
+const fakeURL = "http://fake-alien4cloud"
+
+// FakeQuery describes a query submitted to the fake client
+type FakeQuery struct {
+	ID           string
+	Orchestrator string
+	Collector    string
+	Location     string
+	Parameters   url.Values
+	Body         []byte
+	// Polls is the number of times the query was polled
+	Polls    int
+	Canceled bool
+}
+
+// FakeClient is a yorcprovider.Client backed by an in-memory implementation
+// of the REST API. It is safe for concurrent use
+type FakeClient struct {
+	yorcprovider.Client
+
+	lk             sync.Mutex
+	orchestrators  []string
+	collectors     map[string][]yorcprovider.UsageCollector
+	locations      map[string][]yorcprovider.Location
+	queries        map[string]*FakeQuery
+	queryOrder     []string
+	nextTaskID     int
+	statusSequence []yorcprovider.QueryStatus
+	results        map[string]interface{}
+	failureReason  string
+	pluginVersion  string
+	progress       *int
+}
+
+// NewFakeClient returns a fake client with no orchestrator.
+// Queries are INITIAL on the first poll, RUNNING on the second one,
+// and DONE afterwards
+func NewFakeClient(opts ...yorcprovider.ClientOption) *FakeClient {
+	f := &FakeClient{
+		collectors: make(map[string][]yorcprovider.UsageCollector),
+		locations:  make(map[string][]yorcprovider.Location),
+		queries:    make(map[string]*FakeQuery),
+		statusSequence: []yorcprovider.QueryStatus{
+			yorcprovider.QueryStatusInitial,
+			yorcprovider.QueryStatusRunning,
+			yorcprovider.QueryStatusDone,
+		},
+	}
+
+	httpClient := &http.Client{Transport: roundTripperFunc(f.roundTrip)}
+	client, err := yorcprovider.New(fakeURL, append([]yorcprovider.ClientOption{yorcprovider.WithHTTPClient(httpClient)}, opts...)...)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create fake client: %s", err.Error()))
+	}
+	f.Client = client
+	return f
+}
+
+// AddOrchestrator adds an orchestrator
+func (f *FakeClient) AddOrchestrator(name string) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.orchestrators = append(f.orchestrators, name)
+}
+
+// AddUsageCollector adds a usage collector on an orchestrator
+func (f *FakeClient) AddUsageCollector(orchestratorName string, collector yorcprovider.UsageCollector) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.collectors[orchestratorName] = append(f.collectors[orchestratorName], collector)
+}
+
+// AddLocation adds a location to an orchestrator
+func (f *FakeClient) AddLocation(orchestratorName string, location yorcprovider.Location) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	location.Orchestrator = orchestratorName
+	f.locations[orchestratorName] = append(f.locations[orchestratorName], location)
+}
+
+// SetStatusSequence sets the statuses returned by successive polls of queries
+// submitted afterwards, the last status being returned once the sequence is over
+func (f *FakeClient) SetStatusSequence(statuses ...yorcprovider.QueryStatus) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.statusSequence = statuses
+}
+
+// SetResults sets the results of queries which are done
+func (f *FakeClient) SetResults(results map[string]interface{}) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.results = results
+}
+
+// SetPluginVersion sets the version of the plugin. By default, the version
+// is unknown, as with older versions of the plugin
+func (f *FakeClient) SetPluginVersion(version string) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.pluginVersion = version
+}
+
+// SetFailureReason sets the error message provided for failed queries
+func (f *FakeClient) SetFailureReason(reason string) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.failureReason = reason
+}
+
+// SetProgress sets the progress percentage provided for running queries.
+// By default, no progress is provided
+func (f *FakeClient) SetProgress(progress int) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.progress = &progress
+}
+
+// Queries returns the queries submitted and not deleted, in submission order
+func (f *FakeClient) Queries() []FakeQuery {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	var result []FakeQuery
+	for _, id := range f.queryOrder {
+		if q, ok := f.queries[id]; ok {
+			result = append(result, *q)
+		}
+	}
+	return result
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return fn(request)
+}
+
+// roundTrip serves a request with the in-memory REST API
+func (f *FakeClient) roundTrip(request *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	f.serveHTTP(recorder, request)
+	response := recorder.Result()
+	response.Request = request
+	return response, nil
+}
+
+func (f *FakeClient) serveHTTP(w http.ResponseWriter, request *http.Request) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+
+	var segments []string
+	for _, s := range strings.Split(strings.Trim(request.URL.EscapedPath(), "/"), "/") {
+		segment, err := url.PathUnescape(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		segments = append(segments, segment)
+	}
+
+	path := strings.Join(segments, "/")
+	if path == "login" || path == "logout" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	prefix := strings.Split(strings.Trim(f.RESTPrefix(), "/"), "/")
+	if len(segments) < len(prefix)+1 || strings.Join(segments[:len(prefix)], "/") != strings.Join(prefix, "/") {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No endpoint %s", request.URL.Path))
+		return
+	}
+	if len(segments) == len(prefix)+1 && segments[len(prefix)] == "version" && request.Method == http.MethodGet {
+		f.getPluginVersion(w)
+		return
+	}
+	if segments[len(prefix)] != "orchestrators" {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No endpoint %s", request.URL.Path))
+		return
+	}
+	segments = segments[len(prefix)+1:]
+
+	switch {
+	case len(segments) == 0 && request.Method == http.MethodGet:
+		f.getOrchestrators(w)
+	case len(segments) == 2 && segments[1] == "locations" && request.Method == http.MethodGet:
+		f.getLocations(w, segments[0])
+	case len(segments) == 3 && segments[1] == "registry" && segments[2] == "infra_usage_collectors" && request.Method == http.MethodGet:
+		f.getCollectors(w, segments[0])
+	case len(segments) == 2 && segments[1] == "infra_usage" && request.Method == http.MethodGet:
+		f.getQueries(w, segments[0])
+	case len(segments) == 4 && segments[1] == "infra_usage" && request.Method == http.MethodPost:
+		f.submitQuery(w, request, segments[0], segments[2], segments[3])
+	case len(segments) == 5 && segments[1] == "infra_usage" && segments[3] == "tasks" && request.Method == http.MethodGet:
+		f.getQuery(w, strings.Join(segments, "/"))
+	case len(segments) == 5 && segments[1] == "infra_usage" && segments[3] == "tasks" && request.Method == http.MethodDelete:
+		f.deleteQuery(w, strings.Join(segments, "/"))
+	case len(segments) == 6 && segments[1] == "infra_usage" && segments[3] == "tasks" && segments[5] == "cancel" && request.Method == http.MethodPost:
+		f.cancelQuery(w, strings.Join(segments[:5], "/"))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No endpoint %s %s", request.Method, request.URL.Path))
+	}
+}
+
+func (f *FakeClient) getPluginVersion(w http.ResponseWriter) {
+	if f.pluginVersion == "" {
+		writeError(w, http.StatusNotFound, "No plugin version")
+		return
+	}
+	writeData(w, http.StatusOK, map[string]interface{}{"version": f.pluginVersion})
+}
+
+func (f *FakeClient) hasOrchestrator(name string) bool {
+	for _, o := range f.orchestrators {
+		if o == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *FakeClient) getOrchestrators(w http.ResponseWriter) {
+	var orchestrators []yorcprovider.Orchestrator
+	for _, name := range f.orchestrators {
+		orchestrators = append(orchestrators, yorcprovider.Orchestrator{
+			Name: name,
+			HRef: fmt.Sprintf("%s/orchestrators/%s", f.RESTPrefix(), url.PathEscape(name)),
+		})
+	}
+	writeData(w, http.StatusOK, map[string]interface{}{"orchestrators": orchestrators})
+}
+
+func (f *FakeClient) getCollectors(w http.ResponseWriter, orchestratorName string) {
+	if !f.hasOrchestrator(orchestratorName) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No orchestrator %s", orchestratorName))
+		return
+	}
+	writeData(w, http.StatusOK, map[string]interface{}{"infrastructure_usage_collectors": f.collectors[orchestratorName]})
+}
+
+func (f *FakeClient) getLocations(w http.ResponseWriter, orchestratorName string) {
+	if !f.hasOrchestrator(orchestratorName) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No orchestrator %s", orchestratorName))
+		return
+	}
+	writeData(w, http.StatusOK, map[string]interface{}{"locations": f.locations[orchestratorName]})
+}
+
+func (f *FakeClient) getQueries(w http.ResponseWriter, orchestratorName string) {
+	if !f.hasOrchestrator(orchestratorName) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No orchestrator %s", orchestratorName))
+		return
+	}
+	var tasks []map[string]string
+	for _, id := range f.queryOrder {
+		q, ok := f.queries[id]
+		if !ok || q.Orchestrator != orchestratorName {
+			continue
+		}
+		tasks = append(tasks, map[string]string{
+			"rel":  "task",
+			"href": fmt.Sprintf("%s/orchestrators/%s", f.RESTPrefix(), escapeQueryID(q.ID)),
+			"type": q.Collector,
+		})
+	}
+	writeData(w, http.StatusOK, map[string]interface{}{"tasks": tasks})
+}
+
+func (f *FakeClient) submitQuery(w http.ResponseWriter, request *http.Request, orchestratorName, collectorID, location string) {
+	if !f.hasOrchestrator(orchestratorName) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No orchestrator %s", orchestratorName))
+		return
+	}
+	collectorFound := false
+	for _, c := range f.collectors[orchestratorName] {
+		if c.ID == collectorID {
+			collectorFound = true
+			break
+		}
+	}
+	if !collectorFound {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No collector %s on orchestrator %s", collectorID, orchestratorName))
+		return
+	}
+
+	var body []byte
+	if request.Body != nil {
+		body, _ = ioutil.ReadAll(request.Body)
+	}
+
+	f.nextTaskID++
+	q := &FakeQuery{
+		ID:           strings.Join([]string{orchestratorName, "infra_usage", collectorID, "tasks", strconv.Itoa(f.nextTaskID)}, "/"),
+		Orchestrator: orchestratorName,
+		Collector:    collectorID,
+		Location:     location,
+		Parameters:   request.URL.Query(),
+		Body:         body,
+	}
+	f.queries[q.ID] = q
+	f.queryOrder = append(f.queryOrder, q.ID)
+
+	w.Header().Set("Location", fmt.Sprintf("%s/orchestrators/%s", f.RESTPrefix(), escapeQueryID(q.ID)))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// status returns the current status of a query
+func (f *FakeClient) status(q *FakeQuery) yorcprovider.QueryStatus {
+	if q.Canceled {
+		return yorcprovider.QueryStatusCanceled
+	}
+	if len(f.statusSequence) == 0 {
+		return yorcprovider.QueryStatusDone
+	}
+	i := q.Polls - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(f.statusSequence) {
+		i = len(f.statusSequence) - 1
+	}
+	return f.statusSequence[i]
+}
+
+func (f *FakeClient) getQuery(w http.ResponseWriter, queryID string) {
+	q, ok := f.queries[queryID]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No query %s", queryID))
+		return
+	}
+	q.Polls++
+	status := f.status(q)
+	data := map[string]interface{}{
+		"id":        queryID[strings.LastIndex(queryID, "/")+1:],
+		"target_id": q.Location,
+		"type":      q.Collector,
+		"status":    status,
+	}
+	if status == yorcprovider.QueryStatusDone {
+		data["result_set"] = f.results
+	}
+	if status == yorcprovider.QueryStatusFailed && f.failureReason != "" {
+		data["error"] = f.failureReason
+	}
+	if status == yorcprovider.QueryStatusRunning && f.progress != nil {
+		data["progress"] = *f.progress
+	}
+	writeData(w, http.StatusOK, data)
+}
+
+func (f *FakeClient) deleteQuery(w http.ResponseWriter, queryID string) {
+	if _, ok := f.queries[queryID]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No query %s", queryID))
+		return
+	}
+	delete(f.queries, queryID)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *FakeClient) cancelQuery(w http.ResponseWriter, queryID string) {
+	q, ok := f.queries[queryID]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("No query %s", queryID))
+		return
+	}
+	q.Canceled = true
+	w.WriteHeader(http.StatusOK)
+}
+
+// escapeQueryID escapes each segment of a query ID
+func escapeQueryID(queryID string) string {
+	segments := strings.Split(queryID, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+func writeData(w http.ResponseWriter, statusCode int, data interface{}) {
+	writeJSON(w, statusCode, map[string]interface{}{"data": data})
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]interface{}{
+		"error": yorcprovider.Error{Code: statusCode, Message: message},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	var b bytes.Buffer
+	if err := json.NewEncoder(&b).Encode(v); err != nil {
+		statusCode = http.StatusInternalServerError
+		b.Reset()
+		fmt.Fprintf(&b, `{"error":{"code":500,"message":%q}}`, err.Error())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(b.Bytes())
+}
@@ -0,0 +1,207 @@
+// Copyright 2019 Bull S.A.S. Atos Technologies - Bull, Rue Jean Jaures, B.P.68, 78340, Les Clayes-sous-Bois, France.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package yorcprovidertest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/laurentganne/yorc-provider-go-client/v1/yorcprovider"
+)
+
+// newTestFake returns a fake client with an orchestrator yorc providing a slurm collector
+func newTestFake(opts ...yorcprovider.ClientOption) *FakeClient {
+	f := NewFakeClient(opts...)
+	f.AddOrchestrator("yorc")
+	f.AddUsageCollector("yorc", yorcprovider.UsageCollector{ID: "slurm", Origin: "yorc-slurm"})
+	return f
+}
+
+func TestWaitForQueryCompletionNonPositiveInterval(t *testing.T) {
+	f := newTestFake()
+	f.SetStatusSequence(yorcprovider.QueryStatusDone)
+	service := f.UsageCollectorService()
+
+	queryID, err := service.Query("yorc", "slurm", "hpc", nil)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	for _, interval := range []time.Duration{0, -time.Second} {
+		collection, err := service.WaitForQueryCompletion(context.Background(), queryID.String(), interval)
+		if err != nil {
+			t.Fatalf("WaitForQueryCompletion() with interval %s error = %v", interval, err)
+		}
+		if collection.Status != yorcprovider.QueryStatusDone {
+			t.Errorf("WaitForQueryCompletion() status = %s, want %s", collection.Status, yorcprovider.QueryStatusDone)
+		}
+	}
+}
+
+func TestQueryAllQueryCleanup(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []yorcprovider.ClientOption
+		status    yorcprovider.QueryStatus
+		wantKept  int
+		wantError bool
+	}{
+		{"DoneKeptByDefault", nil, yorcprovider.QueryStatusDone, 2, false},
+		{"FailedKeptByDefault", nil, yorcprovider.QueryStatusFailed, 2, true},
+		{"DoneDeletedWithCleanup", []yorcprovider.ClientOption{yorcprovider.WithQueryCleanup()}, yorcprovider.QueryStatusDone, 0, false},
+		{"FailedKeptWithCleanup", []yorcprovider.ClientOption{yorcprovider.WithQueryCleanup()}, yorcprovider.QueryStatusFailed, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFake(tt.opts...)
+			f.SetStatusSequence(tt.status)
+
+			_, err := f.UsageCollectorService().QueryAll(context.Background(), "yorc", "slurm", []string{"hpc1", "hpc2"}, nil)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("QueryAll() error = %v, want error %t", err, tt.wantError)
+			}
+			if got := len(f.Queries()); got != tt.wantKept {
+				t.Errorf("%d queries kept, want %d", got, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestQueryAllKeepsFailedCollections(t *testing.T) {
+	const reason = "slurm controller unreachable"
+	f := newTestFake()
+	f.SetStatusSequence(yorcprovider.QueryStatusFailed)
+	f.SetFailureReason(reason)
+
+	results, err := f.UsageCollectorService().QueryAll(context.Background(), "yorc", "slurm", []string{"hpc1", "hpc2"}, nil)
+	batchErr, ok := err.(*yorcprovider.BatchError)
+	if !ok || len(batchErr.Errors) != 2 {
+		t.Fatalf("QueryAll() error = %v, want a BatchError for both locations", err)
+	}
+	for _, location := range []string{"hpc1", "hpc2"} {
+		collection := results[location]
+		if collection == nil || collection.Status != yorcprovider.QueryStatusFailed || collection.FailureReason != reason {
+			t.Errorf("QueryAll() collection on %s = %+v, want a FAILED collection with reason %q", location, collection, reason)
+		}
+	}
+}
+
+func TestFakeClientWithRESTPrefix(t *testing.T) {
+	f := newTestFake(yorcprovider.WithRESTPrefix("/rest/yorc-collector-plugin/v2"))
+
+	orchestrators, err := f.OrchestratorService().GetOrchestrators()
+	if err != nil {
+		t.Fatalf("GetOrchestrators() error = %v", err)
+	}
+	if len(orchestrators) != 1 || orchestrators[0].Name != "yorc" {
+		t.Errorf("GetOrchestrators() = %v, want orchestrator yorc", orchestrators)
+	}
+
+	service := f.UsageCollectorService()
+	queryID, err := service.Query("yorc", "slurm", "hpc", nil)
+	if err != nil {
+		t.Fatalf("Query() error = %v", err)
+	}
+	if want := "yorc/infra_usage/slurm/tasks/1"; queryID.String() != want {
+		t.Errorf("Query() = %q, want %q", queryID, want)
+	}
+	if _, err = service.GetCollectedUsageByID(context.Background(), queryID); err != nil {
+		t.Errorf("GetCollectedUsage() error = %v", err)
+	}
+}
+
+func TestDeleteTerminalQueries(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete func(service yorcprovider.UsageCollectorService) (int, error)
+	}{
+		{"DeleteTerminalQueries", func(service yorcprovider.UsageCollectorService) (int, error) {
+			return service.DeleteTerminalQueries(context.Background(), "yorc", "slurm")
+		}},
+		// The age of queries is not exposed by the REST API, terminal queries are deleted
+		{"DeleteQueriesOlderThan", func(service yorcprovider.UsageCollectorService) (int, error) {
+			return service.DeleteQueriesOlderThan(context.Background(), "yorc", "slurm", time.Hour)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFake()
+			f.SetStatusSequence(yorcprovider.QueryStatusRunning, yorcprovider.QueryStatusDone)
+			service := f.UsageCollectorService()
+
+			done, err := service.LegacyQuery("yorc", "slurm", "hpc", nil)
+			if err != nil {
+				t.Fatalf("LegacyQuery() error = %v", err)
+			}
+			// The first poll of a query provides a running status, the next ones a done status
+			if _, err = service.GetQueryStatus(done); err != nil {
+				t.Fatalf("GetQueryStatus() error = %v", err)
+			}
+			running, err := service.LegacyQuery("yorc", "slurm", "hpc", nil)
+			if err != nil {
+				t.Fatalf("LegacyQuery() error = %v", err)
+			}
+
+			deleted, err := tt.delete(service)
+			if err != nil {
+				t.Fatalf("%s() error = %v", tt.name, err)
+			}
+			if deleted != 1 {
+				t.Errorf("%s() = %d, want 1", tt.name, deleted)
+			}
+			ids, err := service.GetQueryIDs("yorc", "slurm")
+			if err != nil {
+				t.Fatalf("GetQueryIDs() error = %v", err)
+			}
+			if len(ids) != 1 || ids[0] != running {
+				t.Errorf("Queries kept = %v, want only the running query %s", ids, running)
+			}
+		})
+	}
+}
+
+func TestQueryWithCollectorOrigin(t *testing.T) {
+	f := newTestFake(yorcprovider.WithQueryValidation())
+	f.AddUsageCollector("yorc", yorcprovider.UsageCollector{ID: "slurm", Origin: "other-slurm"})
+	if _, err := f.UsageCollectorService().Query("yorc", "slurm", "hpc", nil); err == nil || !strings.Contains(err.Error(), "Several collectors") {
+		t.Errorf("Query() of an ambiguous collector error = %v, want an error asking for an origin", err)
+	}
+
+	// Collectors are checked when an origin is selected, even if queries are not validated
+	f = newTestFake()
+	f.AddUsageCollector("yorc", yorcprovider.UsageCollector{ID: "slurm", Origin: "other-slurm"})
+	service := f.UsageCollectorService()
+	ctx := yorcprovider.ContextWithCollectorOrigin(context.Background(), "unknown-slurm")
+	if _, err := service.QueryWithContext(ctx, "yorc", "slurm", "hpc", nil); !errors.Is(err, yorcprovider.ErrCollectorNotFound) {
+		t.Errorf("QueryWithContext() with an unknown origin error = %v, want %v", err, yorcprovider.ErrCollectorNotFound)
+	}
+	if got := len(f.Queries()); got != 0 {
+		t.Fatalf("%d queries submitted, want none", got)
+	}
+
+	ctx = yorcprovider.ContextWithCollectorOrigin(context.Background(), "other-slurm")
+	if _, err := service.QueryWithContext(ctx, "yorc", "slurm", "hpc", nil); err != nil {
+		t.Errorf("QueryWithContext() with origin other-slurm error = %v", err)
+	}
+	if _, err := service.QueryAll(ctx, "yorc", "slurm", []string{"hpc1", "hpc2"}, nil); err != nil {
+		t.Errorf("QueryAll() with origin other-slurm error = %v", err)
+	}
+	if got := len(f.Queries()); got != 3 {
+		t.Errorf("%d queries submitted, want 3", got)
+	}
+}